@@ -0,0 +1,271 @@
+/**
+cormgen 根据结构体定义生成不使用反射的扫描代码，配合 go generate 使用：
+	//go:generate go run github.com/chu108/corm/cmd/cormgen -type Users,Groups
+为每个结构体生成：
+	UsersTable   数据表名，默认为结构体名的蛇形命名，可通过 -table 指定
+	UsersColumns 查询字段，顺序与 ScanRow 一致
+	Columns、ScanRow、InsertMap、UpdateMap 方法
+	UsersList    列表类型，用于 db.Tab(UsersTable).Find(&list)
+字段名默认为蛇形命名，可通过标签指定：
+	Name    string `db:"nickname"`  指定字段名
+	Id      int64  `db:"id,pk"`     指定主键，默认为 id 字段，InsertMap、UpdateMap 不包含主键
+	GroupId int64  `db:"-"`         忽略该字段
+*/
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+func main() {
+	typeNames := flag.String("type", "", "结构体名，多个用逗号分隔")
+	file := flag.String("file", os.Getenv("GOFILE"), "结构体所在文件，默认为 go generate 的当前文件")
+	output := flag.String("output", "", "输出文件，默认为 <文件名>_cormgen.go，测试文件为 <文件名>_cormgen_test.go")
+	table := flag.String("table", "", "数据表名，只能用于单个结构体")
+	flag.Parse()
+
+	if err := run(*file, *output, *typeNames, *table); err != nil {
+		fmt.Fprintln(os.Stderr, "cormgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(file, output, typeNames, table string) error {
+	if file == "" {
+		return fmt.Errorf("缺少 -file 参数，或通过 go generate 运行")
+	}
+	if typeNames == "" {
+		return fmt.Errorf("缺少 -type 参数")
+	}
+	names := strings.Split(typeNames, ",")
+	if table != "" && len(names) > 1 {
+		return fmt.Errorf("-table 只能用于单个结构体")
+	}
+
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	code, err := generate(file, src, names, table)
+	if err != nil {
+		return err
+	}
+	if output == "" {
+		output = outputName(file)
+	}
+	return os.WriteFile(output, code, 0644)
+}
+
+//默认输出文件，测试文件中的结构体生成到测试文件
+func outputName(file string) string {
+	if strings.HasSuffix(file, "_test.go") {
+		return strings.TrimSuffix(file, "_test.go") + "_cormgen_test.go"
+	}
+	return strings.TrimSuffix(file, ".go") + "_cormgen.go"
+}
+
+type model struct {
+	Name    string
+	Table   string
+	Fields  []column
+	Primary string
+}
+
+type column struct {
+	Field  string
+	Column string
+}
+
+//解析源文件并生成代码
+func generate(file string, src []byte, names []string, table string) ([]byte, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), file, src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+
+	models := make([]*model, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		st := findStruct(f, name)
+		if st == nil {
+			return nil, fmt.Errorf("%s 中没有结构体 %s", file, name)
+		}
+		m, err := parseStruct(name, st)
+		if err != nil {
+			return nil, err
+		}
+		m.Table = table
+		if m.Table == "" {
+			m.Table = snakeCase(name)
+		}
+		models = append(models, m)
+	}
+
+	var buf bytes.Buffer
+	err = codeTemplate.Execute(&buf, struct {
+		Package string
+		Models  []*model
+	}{f.Name.Name, models})
+	if err != nil {
+		return nil, err
+	}
+	code, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("格式化生成的代码出错：%w", err)
+	}
+	return code, nil
+}
+
+func findStruct(f *ast.File, name string) *ast.StructType {
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+			if ts.Name.Name != name {
+				continue
+			}
+			if st, ok := ts.Type.(*ast.StructType); ok {
+				return st
+			}
+		}
+	}
+	return nil
+}
+
+//解析字段，跳过未导出、嵌入及 db:"-" 的字段
+func parseStruct(name string, st *ast.StructType) (*model, error) {
+	m := &model{Name: name}
+	seen := make(map[string]bool)
+	for _, f := range st.Fields.List {
+		tag := ""
+		if f.Tag != nil {
+			raw, err := strconv.Unquote(f.Tag.Value)
+			if err != nil {
+				return nil, err
+			}
+			tag = reflect.StructTag(raw).Get("db")
+		}
+		if tag == "-" {
+			continue
+		}
+		options := strings.Split(tag, ",")
+		for _, ident := range f.Names {
+			if !ident.IsExported() {
+				continue
+			}
+			col := column{Field: ident.Name, Column: options[0]}
+			if col.Column == "" || len(f.Names) > 1 {
+				col.Column = snakeCase(ident.Name)
+			}
+			if seen[col.Column] {
+				return nil, fmt.Errorf("%s 的字段 %s 重复", name, col.Column)
+			}
+			seen[col.Column] = true
+			for _, opt := range options[1:] {
+				switch opt {
+				case "pk":
+					if m.Primary != "" {
+						return nil, fmt.Errorf("%s 只能有一个主键", name)
+					}
+					m.Primary = col.Column
+				default:
+					return nil, fmt.Errorf("%s.%s 未知的标签选项 %q", name, ident.Name, opt)
+				}
+			}
+			m.Fields = append(m.Fields, col)
+		}
+	}
+	if len(m.Fields) == 0 {
+		return nil, fmt.Errorf("%s 没有可用的字段", name)
+	}
+	if m.Primary == "" && seen["id"] {
+		m.Primary = "id"
+	}
+	return m, nil
+}
+
+//驼峰转蛇形：GroupId -> group_id、UserID -> user_id、HTTPCode -> http_code
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for k, r := range runes {
+		if unicode.IsUpper(r) {
+			if k > 0 && (unicode.IsLower(runes[k-1]) || unicode.IsDigit(runes[k-1]) ||
+				(k+1 < len(runes) && unicode.IsLower(runes[k+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+var codeTemplate = template.Must(template.New("cormgen").Parse(`// Code generated by cormgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import "database/sql"
+{{range .Models}}{{$m := .}}
+//{{.Name}} 对应的数据表
+const {{.Name}}Table = "{{.Table}}"
+
+//{{.Name}} 的查询字段，顺序与 ScanRow 一致
+var {{.Name}}Columns = []string{ {{- range $k, $f := .Fields}}{{if $k}}, {{end}}"{{$f.Column}}"{{end -}} }
+
+//查询字段
+func (*{{.Name}}) Columns() []string {
+	return {{.Name}}Columns
+}
+
+//按 {{.Name}}Columns 的顺序扫描当前行
+func (m *{{.Name}}) ScanRow(rows *sql.Rows) error {
+	return rows.Scan({{range $k, $f := .Fields}}{{if $k}}, {{end}}&m.{{$f.Field}}{{end}})
+}
+
+//插入数据{{if .Primary}}，不包含主键 {{.Primary}}{{end}}
+func (m *{{.Name}}) InsertMap() map[string]interface{} {
+	return map[string]interface{}{
+	{{- range .Fields}}{{if ne .Column $m.Primary}}
+		"{{.Column}}": m.{{.Field}},{{end}}{{end}}
+	}
+}
+
+//修改数据{{if .Primary}}，不包含主键 {{.Primary}}{{end}}
+func (m *{{.Name}}) UpdateMap() map[string]interface{} {
+	return m.InsertMap()
+}
+
+//{{.Name}} 列表，用于 Find
+type {{.Name}}List []*{{.Name}}
+
+//查询字段
+func (*{{.Name}}List) Columns() []string {
+	return {{.Name}}Columns
+}
+
+//扫描当前行并追加到列表
+func (l *{{.Name}}List) ScanAppend(rows *sql.Rows) error {
+	m := new({{.Name}})
+	if err := m.ScanRow(rows); err != nil {
+		return err
+	}
+	*l = append(*l, m)
+	return nil
+}
+{{end}}`))
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSnakeCase(t *testing.T) {
+	for name, want := range map[string]string{
+		"Id":        "id",
+		"GroupId":   "group_id",
+		"UserID":    "user_id",
+		"HTTPCode":  "http_code",
+		"CreatedAt": "created_at",
+		"Address2":  "address2",
+	} {
+		if got := snakeCase(name); got != want {
+			t.Errorf("snakeCase(%q) = %q，应为 %q", name, got, want)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	src := "package demo\n\ntype Orders struct {\n" +
+		"\tNo     string `db:\"order_no,pk\"`\n" +
+		"\tUserId int64\n" +
+		"\tAmount string\n" +
+		"\tRemark string `db:\"-\"`\n" +
+		"\tstatus int\n" +
+		"}\n"
+	code, err := generate("orders.go", []byte(src), []string{"Orders"}, "order_list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`const OrdersTable = "order_list"`,
+		`var OrdersColumns = []string{"order_no", "user_id", "amount"}`,
+		`rows.Scan(&m.No, &m.UserId, &m.Amount)`,
+		`type OrdersList []*Orders`,
+	} {
+		if !strings.Contains(string(code), want) {
+			t.Errorf("生成的代码缺少 %s：\n%s", want, code)
+		}
+	}
+	if strings.Contains(string(code), `"order_no": m.No`) {
+		t.Errorf("InsertMap 不应包含主键：\n%s", code)
+	}
+
+	for _, src := range []string{
+		"package demo\n\ntype Orders struct{ Id int64 `db:\"id,pk\"`; No string `db:\"no,pk\"` }\n",
+		"package demo\n\ntype Orders struct{ Id int64 `db:\"id,auto\"` }\n",
+		"package demo\n\ntype Orders struct{ Id int64; ID int64 }\n",
+		"package demo\n\ntype Orders struct{ id int64 }\n",
+		"package demo\n\ntype Users struct{ Id int64 }\n",
+	} {
+		if _, err := generate("orders.go", []byte(src), []string{"Orders"}, ""); err == nil {
+			t.Errorf("应返回错误：%s", src)
+		}
+	}
+}
+
+func TestOutputName(t *testing.T) {
+	if got := outputName("models.go"); got != "models_cormgen.go" {
+		t.Error(got)
+	}
+	if got := outputName("corm_test.go"); got != "corm_cormgen_test.go" {
+		t.Error(got)
+	}
+}
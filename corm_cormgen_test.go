@@ -0,0 +1,55 @@
+// Code generated by cormgen. DO NOT EDIT.
+
+package corm
+
+import "database/sql"
+
+// Users 对应的数据表
+const UsersTable = "users"
+
+// Users 的查询字段，顺序与 ScanRow 一致
+var UsersColumns = []string{"id", "name", "age", "phone", "created_at", "updated_at"}
+
+// 查询字段
+func (*Users) Columns() []string {
+	return UsersColumns
+}
+
+// 按 UsersColumns 的顺序扫描当前行
+func (m *Users) ScanRow(rows *sql.Rows) error {
+	return rows.Scan(&m.Id, &m.Name, &m.Age, &m.Phone, &m.CreatedAt, &m.UpdatedAt)
+}
+
+// 插入数据，不包含主键 id
+func (m *Users) InsertMap() map[string]interface{} {
+	return map[string]interface{}{
+		"name":       m.Name,
+		"age":        m.Age,
+		"phone":      m.Phone,
+		"created_at": m.CreatedAt,
+		"updated_at": m.UpdatedAt,
+	}
+}
+
+// 修改数据，不包含主键 id
+func (m *Users) UpdateMap() map[string]interface{} {
+	return m.InsertMap()
+}
+
+// Users 列表，用于 Find
+type UsersList []*Users
+
+// 查询字段
+func (*UsersList) Columns() []string {
+	return UsersColumns
+}
+
+// 扫描当前行并追加到列表
+func (l *UsersList) ScanAppend(rows *sql.Rows) error {
+	m := new(Users)
+	if err := m.ScanRow(rows); err != nil {
+		return err
+	}
+	*l = append(*l, m)
+	return nil
+}
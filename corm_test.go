@@ -2,16 +2,20 @@ package corm
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
+	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 )
 
 var masterDB *sql.DB
 var err error
 
-//用户
+//用户，扫描代码见 corm_cormgen_test.go
+//go:generate go run ./cmd/cormgen -type Users
 type Users struct {
 	Id        int64
 	Name      string
@@ -19,7 +23,7 @@ type Users struct {
 	Phone     string
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	GroupId   int64
+	GroupId   int64 `db:"-"`
 }
 
 //组
@@ -121,6 +125,130 @@ func TestWhere(t *testing.T) {
 	}
 }
 
+func TestRaw(t *testing.T) {
+	fmt.Println("-------------------whereRaw 绑定参数-------------------")
+	data := make([]*Users, 0)
+	err = GetDb(masterDB).Tab("users").Select("name", "age", "phone").
+		Where("age", ">=", 20).
+		WhereRaw("phone = ? OR name = ?", "18310953333", "王五").
+		Get(func(rows *sql.Rows) {
+			user := new(Users)
+			_ = rows.Scan(&user.Name, &user.Age, &user.Phone)
+			data = append(data, user)
+		})
+	retErr(err)
+	for k, v := range data {
+		fmt.Println(k, v.Name, v.Age, v.Phone)
+	}
+
+	fmt.Println("-------------------原生语句-------------------")
+	name := ""
+	raw := GetDb(masterDB).Raw("SELECT name FROM users WHERE id = ?", 10)
+	retErr(raw.First(&name))
+	fmt.Println(name)
+	//Raw 可重复执行
+	retErr(raw.First(&name))
+	fmt.Println(name)
+
+	//参数错误在每次执行时都会返回
+	bad := GetDb(masterDB).Raw("SELECT name FROM users WHERE id = ?")
+	for i := 0; i < 2; i++ {
+		if err := bad.First(&name); err == nil {
+			t.Fatal("占位符数量不一致时应返回错误")
+		}
+	}
+
+	err = GetDb(masterDB).Transaction(func(dbTrans *Db) error {
+		_, err := dbTrans.Raw("UPDATE users SET age = age + 1 WHERE id = ?", 10).Exec()
+		return err
+	})
+	retErr(err)
+}
+
+func TestNamed(t *testing.T) {
+	fmt.Println("-------------------命名参数-------------------")
+	params := map[string]interface{}{
+		"age":   20,
+		"start": "2017-08-08 00:00:00",
+		"ids":   []int64{9, 10, 11},
+	}
+	data := make([]*Users, 0)
+	err = GetDb(masterDB).Tab("users").
+		SelectRaw("name, IF(age > :age, 1, 0) AS adult", params).
+		WhereRaw("created_at >= :start AND (age > :age OR id IN (:ids))", params).
+		Get(func(rows *sql.Rows) {
+			user := new(Users)
+			_ = rows.Scan(&user.Name, &user.Age)
+			data = append(data, user)
+		})
+	retErr(err)
+	for k, v := range data {
+		fmt.Println(k, v.Name, v.Age)
+	}
+
+	count := 0
+	err = GetDb(masterDB).Raw("SELECT COUNT(*) FROM users WHERE id IN (:ids)", params).First(&count)
+	retErr(err)
+	fmt.Println("总数：", count)
+}
+
+func TestNamedQuery(t *testing.T) {
+	fmt.Println("-------------------命名查询-------------------")
+	err := LoadQueriesFS(fstest.MapFS{
+		"queries/report.sql": &fstest.MapFile{Data: []byte(`
+-- 用户报表
+-- name: users_by_age
+SELECT name, age FROM users
+WHERE age >= :age AND id IN (:ids)
+ORDER BY id DESC;
+
+-- name: users_count
+SELECT COUNT(*) FROM users WHERE age >= :age;
+`)},
+	}, "queries")
+	retErr(err)
+
+	params := map[string]interface{}{"age": 20, "ids": []int{9, 10}}
+	data := make([]*Users, 0)
+	err = GetDb(masterDB).NamedQuery("users_by_age", params).Get(func(rows *sql.Rows) {
+		user := new(Users)
+		_ = rows.Scan(&user.Name, &user.Age)
+		data = append(data, user)
+	})
+	retErr(err)
+	for k, v := range data {
+		fmt.Println(k, v.Name, v.Age)
+	}
+
+	count := 0
+	err = GetDb(masterDB).NamedQuery("users_count", params).First(&count)
+	retErr(err)
+	fmt.Println("总数：", count)
+}
+
+func TestWhereNull(t *testing.T) {
+	fmt.Println("-------------------NULL 条件-------------------")
+	count, err := GetDb(masterDB).Tab("users").WhereNull("updated_at").Count()
+	retErr(err)
+	fmt.Println("updated_at 为 NULL：", count)
+
+	var deletedAt sql.NullTime
+	count, err = GetDb(masterDB).Tab("users").WhereEqual("updated_at", deletedAt).WhereNotNull("created_at").Count()
+	retErr(err)
+	fmt.Println("updated_at 为 NULL：", count)
+
+	//nil 指针按 NULL 处理
+	var phone *sql.NullString
+	sqlStr := GetDb(masterDB).Tab("users").Where("phone", "=", phone).Where("name", "<>", (*string)(nil)).whereToSql()
+	if !strings.Contains(sqlStr, "`phone` IS NULL") || !strings.Contains(sqlStr, "`name` IS NOT NULL") {
+		t.Fatalf("nil 指针应转换为 NULL 条件：%s", sqlStr)
+	}
+
+	count, err = GetDb(masterDB).Tab("users").WhereNullSafe("phone", nil).Count()
+	retErr(err)
+	fmt.Println("phone <=> NULL：", count)
+}
+
 func TestJoin(t *testing.T) {
 	fmt.Println("-------------------join-------------------")
 	data := make([]*Users, 0)
@@ -154,6 +282,321 @@ func TestJoin(t *testing.T) {
 	}
 }
 
+func TestOrderBy(t *testing.T) {
+	fmt.Println("-------------------排序-------------------")
+	//排序字段、方向来自用户输入时会被校验
+	sortField, sortBy := "age", "desc"
+	data := make([]*Users, 0)
+	err = GetDb(masterDB).Tab("users u").Select("u.name", "u.age").
+		OrderBy(sortField, sortBy).
+		OrderByRaw("FIELD(u.id, 9, 10) DESC").
+		Get(func(rows *sql.Rows) {
+			user := new(Users)
+			_ = rows.Scan(&user.Name, &user.Age)
+			data = append(data, user)
+		})
+	retErr(err)
+	for k, v := range data {
+		fmt.Println(k, v.Name, v.Age)
+	}
+
+	fmt.Println("-------------------非法排序方向-------------------")
+	_, err = GetDb(masterDB).Tab("users").OrderBy("id", "desc; DROP TABLE users").Count()
+	fmt.Println(err)
+}
+
+func TestWhereColumn(t *testing.T) {
+	fmt.Println("-------------------字段比较-------------------")
+	data := make([]*Users, 0)
+	err = GetDb(masterDB).Tab("users u").
+		JoinOn("user_groups ug", func(on *Db) {
+			on.WhereColumn("u.id", "=", "ug.user_id").Where("ug.group_id", ">", 0)
+		}).
+		Select("u.name", "u.age", "ug.group_id").
+		WhereColumn("u.updated_at", ">", "u.created_at").
+		WhereGroup(func(q *Db) {
+			q.Where("u.age", ">", 30).OrWhereColumn("u.id", "=", "ug.group_id")
+		}).
+		Get(func(rows *sql.Rows) {
+			user := new(Users)
+			_ = rows.Scan(&user.Name, &user.Age, &user.GroupId)
+			data = append(data, user)
+		})
+	retErr(err)
+	for k, v := range data {
+		fmt.Println(k, v.Name, v.Age, v.GroupId)
+	}
+}
+
+func TestSubQuery(t *testing.T) {
+	fmt.Println("-------------------子查询-------------------")
+	inGroup := GetDb(masterDB).Tab("user_groups").Select("user_id").Where("group_id", "=", 1)
+	groupCount := GetDb(masterDB).Tab("user_groups ug").SelectRaw("COUNT(*)").WhereColumn("ug.user_id", "=", "u.id")
+	data := make([]*Users, 0)
+	err = GetDb(masterDB).Tab("users u").Select("u.name").SelectSub(groupCount, "group_count").
+		WhereInSub("u.id", inGroup).
+		WhereExists(GetDb(masterDB).Tab("user_groups ug").WhereColumn("ug.user_id", "=", "u.id")).
+		Get(func(rows *sql.Rows) {
+			user := new(Users)
+			_ = rows.Scan(&user.Name, &user.GroupId)
+			data = append(data, user)
+		})
+	retErr(err)
+	for k, v := range data {
+		fmt.Println(k, v.Name, v.GroupId)
+	}
+
+	fmt.Println("-------------------派生表-------------------")
+	adults := GetDb(masterDB).Tab("users").Select("id", "name", "age").Where("age", ">=", 18)
+	count, err := GetDb(masterDB).TabSub(adults, "t").Where("t.age", "<", 30).Count()
+	retErr(err)
+	fmt.Println("总数：", count)
+}
+
+func TestUnion(t *testing.T) {
+	fmt.Println("-------------------联合查询-------------------")
+	young := GetDb(masterDB).Tab("users").Select("name", "age").Where("age", "<", 20)
+	data := make([]*Users, 0)
+	total, _, err := GetDb(masterDB).Tab("users").Select("name", "age").Where("age", ">", 30).
+		UnionAll(young).
+		OrderBy("age", "desc").
+		GetPage(1, 10, func(rows *sql.Rows) {
+			user := new(Users)
+			_ = rows.Scan(&user.Name, &user.Age)
+			data = append(data, user)
+		})
+	retErr(err)
+	fmt.Println("总记录数：", total)
+	for k, v := range data {
+		fmt.Println(k, v.Name, v.Age)
+	}
+
+	//Union 前的 OrderBy、Limit 作用于基础查询，之后的作用于联合结果
+	oldest := GetDb(masterDB).Tab("users").Select("name", "age").OrderBy("age", "desc").Limit(1).
+		UnionAll(GetDb(masterDB).Tab("users").Select("name", "age").OrderBy("age", "asc").Limit(1)).
+		OrderBy("age", "asc")
+	sqlStr := oldest.whereToSql()
+	fmt.Println(sqlStr)
+	if !strings.Contains(sqlStr, "ORDER BY `age` DESC LIMIT 1) UNION ALL (") ||
+		!strings.HasSuffix(strings.TrimSpace(sqlStr), ") ORDER BY `age` ASC") {
+		t.Fatalf("Union 前的排序、分页应在基础查询内：%s", sqlStr)
+	}
+	data = data[:0]
+	err = oldest.Query(func(rows *sql.Rows) error {
+		user := new(Users)
+		err := rows.Scan(&user.Name, &user.Age)
+		data = append(data, user)
+		return err
+	})
+	retErr(err)
+	if len(data) != 2 || data[0].Age > data[1].Age {
+		t.Fatalf("应返回年龄最小、最大的 2 条记录：%d", len(data))
+	}
+}
+
+func TestSelectExpr(t *testing.T) {
+	fmt.Println("-------------------表达式查询-------------------")
+	err := GetDb(masterDB).Tab("users").Select("name").
+		SelectRaw("IF(age >= ?, 1, 0) AS adult", 18).
+		SelectExpr(IfNull(Col("phone"), "无"), "phone").
+		SelectExpr(Case().When("age", "<", 18, "少年").When("age", "<", 60, "成年").Else("老年"), "stage").
+		Where("id", ">", 0).
+		Get(func(rows *sql.Rows) {
+			var name, phone, stage string
+			var adult int
+			_ = rows.Scan(&name, &adult, &phone, &stage)
+			fmt.Println(name, adult, phone, stage)
+		})
+	retErr(err)
+
+	//nil 表达式返回错误而不是 panic
+	var c *CaseExpr
+	_, err = GetDb(masterDB).Tab("users").SelectExpr(c, "stage").SelectExpr(IfNull(c, "无"), "name").Count()
+	if err == nil || !strings.Contains(err.Error(), "Case:") {
+		t.Fatalf("nil 表达式应返回错误：%v", err)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	fmt.Println("-------------------类型聚合-------------------")
+	avg, err := GetDb(masterDB).Tab("users").Avg("age")
+	retErr(err)
+	fmt.Println("平均年龄：", avg)
+
+	sum, err := GetDb(masterDB).Tab("users").SumDecimal("age")
+	retErr(err)
+	fmt.Println("年龄总和：", sum.FloatString(2))
+
+	//MaxTime、MinTime 需在连接串中设置 parseTime=true
+	conn, err := sql.Open("mysql", "root:g2q3g5p8@tcp(127.0.0.1:3306)/corm_demo?parseTime=true")
+	retErr(err)
+	defer conn.Close()
+	first, err := GetDb(conn).Tab("users").MinTime("created_at")
+	retErr(err)
+	last, err := GetDb(conn).Tab("users").MaxTime("created_at")
+	retErr(err)
+	fmt.Println("创建时间：", first, last)
+	if last.Before(first) {
+		t.Fatalf("MaxTime %v 不能早于 MinTime %v", last, first)
+	}
+
+	minAge, err := GetDb(masterDB).Tab("users").MinFloat("age")
+	retErr(err)
+	maxAge, err := GetDb(masterDB).Tab("users").MaxFloat("age")
+	retErr(err)
+	fmt.Println("年龄：", minAge, maxAge)
+	if maxAge < minAge {
+		t.Fatalf("MaxFloat %v 不能小于 MinFloat %v", maxAge, minAge)
+	}
+
+	minName, err := GetDb(masterDB).Tab("users").MinStr("name")
+	retErr(err)
+	maxName, err := GetDb(masterDB).Tab("users").MaxStr("name")
+	retErr(err)
+	fmt.Println("姓名：", minName, maxName)
+
+	//没有记录时聚合结果为 NULL，返回 ErrNotFound
+	empty := func() *Db { return GetDb(conn).Tab("users").Where("id", "<", 0) }
+	for name, fn := range map[string]func() error{
+		"MaxTime":  func() error { _, err := empty().MaxTime("created_at"); return err },
+		"MinTime":  func() error { _, err := empty().MinTime("created_at"); return err },
+		"MaxFloat": func() error { _, err := empty().MaxFloat("age"); return err },
+		"MinFloat": func() error { _, err := empty().MinFloat("age"); return err },
+		"MaxStr":   func() error { _, err := empty().MaxStr("name"); return err },
+		"MinStr":   func() error { _, err := empty().MinStr("name"); return err },
+	} {
+		if err := fn(); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("%s 没有记录时应返回 ErrNotFound：%v", name, err)
+		}
+	}
+}
+
+func TestAggregateGroup(t *testing.T) {
+	fmt.Println("-------------------分组聚合-------------------")
+	rows, err := GetDb(masterDB).Tab("users").
+		GroupBy("age").
+		Having("n", ">", 0).
+		OrderBy("n", "desc").
+		Aggregate(Count("*").As("n"), Sum("id").As("total"), Max("created_at"))
+	retErr(err)
+	for _, row := range rows {
+		fmt.Println(row.Group["age"], row.Int64("n"), row.Decimal("total"), row.String("max_created_at"))
+	}
+	if row := rows.Find(20); row != nil {
+		fmt.Println("20 岁人数：", row.Int64("n"))
+	}
+}
+
+func TestGroupCount(t *testing.T) {
+	fmt.Println("-------------------分组统计-------------------")
+	groups, err := GetDb(masterDB).Tab("users").Select("age").SelectRaw("COUNT(*) AS n").
+		GroupBy("age").
+		Having("n", ">", 1).
+		Count()
+	retErr(err)
+	fmt.Println("人数大于 1 的年龄数：", groups)
+
+	//未指定字段时子查询只查询分组字段
+	ageGroups, err := GetDb(masterDB).Tab("users").GroupBy("age").Count()
+	retErr(err)
+	fmt.Println("年龄分组数：", ageGroups)
+
+	distinct, err := GetDb(masterDB).Tab("users").Distinct().Select("age").Count()
+	retErr(err)
+	fmt.Println("不同年龄数：", distinct)
+
+	ages, err := GetDb(masterDB).Tab("users").Where("age", ">", 0).CountDistinct("age")
+	retErr(err)
+	fmt.Println("不同年龄数：", ages)
+}
+
+func TestWindow(t *testing.T) {
+	fmt.Println("-------------------窗口函数-------------------")
+	err := GetDb(masterDB).Tab("users").Select("name", "age").
+		SelectWindow("rn", RowNumber().PartitionBy("age").OrderBy("created_at", "desc")).
+		SelectWindow("total", SumOver("age").Over("w").Frame("ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW")).
+		Window("w", Window().OrderBy("id", "asc")).
+		Get(func(rows *sql.Rows) {
+			user := new(Users)
+			var rn, total int64
+			_ = rows.Scan(&user.Name, &user.Age, &rn, &total)
+			fmt.Println(rn, user.Name, user.Age, total)
+		})
+	retErr(err)
+}
+
+func TestWith(t *testing.T) {
+	fmt.Println("-------------------公用表表达式-------------------")
+	adults := GetDb(masterDB).Tab("users").Select("id", "name", "age").Where("age", ">=", 18)
+	data := make([]*Users, 0)
+	err := GetDb(masterDB).Tab("adults").With("adults", adults).Select("name", "age").Where("age", "<", 30).
+		Get(func(rows *sql.Rows) {
+			user := new(Users)
+			_ = rows.Scan(&user.Name, &user.Age)
+			data = append(data, user)
+		})
+	retErr(err)
+	for k, v := range data {
+		fmt.Println(k, v.Name, v.Age)
+	}
+
+	fmt.Println("-------------------递归公用表表达式-------------------")
+	//从 id 为 8 的用户开始，查找 id 连续的用户
+	anchor := GetDb(masterDB).Tab("users").Select("id", "name").Where("id", "=", 8)
+	recursive := GetDb(masterDB).Tab("users u").Select("u.id", "u.name").
+		JoinOn("chain t", func(on *Db) {
+			on.WhereRaw("u.id = t.id + 1")
+		})
+	count, err := GetDb(masterDB).Tab("chain").WithRecursive("chain", []string{"id", "name"}, anchor, recursive).Count()
+	retErr(err)
+	fmt.Println("连续用户数：", count)
+}
+
+func TestSeekPage(t *testing.T) {
+	fmt.Println("------------------- 游标分页 -------------------")
+	order := []SeekColumn{SeekDesc("age"), SeekDesc("id")}
+	scan := func(rows *sql.Rows) ([]interface{}, error) {
+		user := new(Users)
+		err := rows.Scan(&user.Id, &user.Name, &user.Age)
+		fmt.Println(user.Id, user.Name, user.Age)
+		return []interface{}{user.Age, user.Id}, err
+	}
+	page, err := GetDb(masterDB).Tab("users").Select("id", "name", "age").SeekPage(order, "", 2, scan)
+	retErr(err)
+	fmt.Println("------------------- 下一页 -------------------")
+	next, err := GetDb(masterDB).Tab("users").Select("id", "name", "age").SeekPage(order, page.Next, 2, scan)
+	retErr(err)
+	fmt.Println("------------------- 上一页 -------------------")
+	_, err = GetDb(masterDB).Tab("users").Select("id", "name", "age").SeekPage(order, next.Prev, 2, scan)
+	retErr(err)
+
+	fmt.Println("------------------- OR 条件 -------------------")
+	//游标条件需作用于全部 OR 条件，否则满足 age > 20 的数据每页都会返回
+	total, err := GetDb(masterDB).Tab("users").Where("age", ">", 20).OrWhere("id", "<", 3).Count()
+	retErr(err)
+	seen := make(map[int64]bool)
+	after := ""
+	for n := int64(0); n <= total; n++ {
+		page, err := GetDb(masterDB).Tab("users").Select("id", "name", "age").Where("age", ">", 20).OrWhere("id", "<", 3).
+			SeekPage(order, after, 2, func(rows *sql.Rows) ([]interface{}, error) {
+				keys, err := scan(rows)
+				if err == nil && seen[keys[1].(int64)] {
+					err = fmt.Errorf("重复的记录 %d", keys[1])
+				}
+				seen[keys[1].(int64)] = true
+				return keys, err
+			})
+		retErr(err)
+		if !page.HasNext {
+			break
+		}
+		after = page.Next
+	}
+	if int64(len(seen)) != total {
+		t.Fatalf("游标分页 %d 条，应为 %d", len(seen), total)
+	}
+}
+
 func TestSelectPage(t *testing.T) {
 	fmt.Println("------------------- 分页查询 -------------------")
 	//当前页数
@@ -164,7 +607,7 @@ func TestSelectPage(t *testing.T) {
 	var total int64
 	//数据
 	data := make([]*Users, 0)
-	total, err = GetDb(masterDB).Tab("users").Select("name", "age", "phone").OrderBy("id", "desc").
+	total, _, err = GetDb(masterDB).Tab("users").Select("name", "age", "phone").OrderBy("id", "desc").
 		GetPage(page, pageCount, func(rows *sql.Rows) {
 			user := new(Users)
 			_ = rows.Scan(&user.Name, &user.Age, &user.Phone)
@@ -177,6 +620,184 @@ func TestSelectPage(t *testing.T) {
 	}
 }
 
+func TestPaginate(t *testing.T) {
+	fmt.Println("------------------- 分页结果 -------------------")
+	data := make([]*Users, 0)
+	scan := func(scan Scanner) error {
+		user := new(Users)
+		err := scan(&user.Name, &user.Age)
+		data = append(data, user)
+		return err
+	}
+	total, err := GetDb(masterDB).Tab("users").Count()
+	retErr(err)
+	page, err := GetDb(masterDB).Tab("users").Select("name", "age").OrderBy("id", "desc").Paginate(2, 2, scan)
+	retErr(err)
+	fmt.Println("总记录数：", page.Total, "总页数：", page.Pages, "下一页：", page.HasNext, "上一页：", page.HasPrev)
+	//第 2 页统计总数时不能带上分页的 Offset
+	if page.Total != total || !page.HasPrev {
+		t.Fatalf("第 2 页总记录数 %d，应为 %d", page.Total, total)
+	}
+
+	page, err = GetDb(masterDB).Tab("users").Select("name", "age").OrderBy("id", "desc").Paginate(2, 2, scan, WithoutCount())
+	retErr(err)
+	fmt.Println("不统计总数，下一页：", page.HasNext)
+
+	page, err = GetDb(masterDB).Tab("users").Select("name", "age").OrderBy("id", "desc").Paginate(2, 2, scan, WithWindowCount())
+	retErr(err)
+	fmt.Println("COUNT(*) OVER() 总记录数：", page.Total)
+	if page.Total != total {
+		t.Fatalf("COUNT(*) OVER() 总记录数 %d，应为 %d", page.Total, total)
+	}
+
+	//超出最后一页时 WithWindowCount 再执行一次 Count
+	page, err = GetDb(masterDB).Tab("users").OrderBy("id", "desc").Paginate(int(total)+1, 1, func(scan Scanner) error { return nil }, WithWindowCount())
+	retErr(err)
+	if page.Total != total || page.HasNext {
+		t.Fatalf("超出最后一页总记录数 %d，应为 %d", page.Total, total)
+	}
+
+	page, err = GetDb(masterDB).Tab("users").Select("name", "age").OrderBy("id", "desc").Paginate(2, 2, scan, WithApproxCount())
+	retErr(err)
+	fmt.Println("估算总记录数：", page.Total)
+	if !page.Approximate || page.Total < 0 {
+		t.Fatalf("估算总记录数 %d，Approximate 应为 true", page.Total)
+	}
+	//有条件、关联时使用 EXPLAIN 估算
+	page, err = GetDb(masterDB).Tab("users u").Join("user_groups ug", "u.id = ug.user_id").Select("u.name", "u.age").
+		Where("u.age", ">", 0).Paginate(1, 2, scan, WithApproxCount())
+	retErr(err)
+	fmt.Println("关联查询估算总记录数：", page.Total)
+	if !page.Approximate || page.Total < 0 {
+		t.Fatalf("关联查询估算总记录数 %d，Approximate 应为 true", page.Total)
+	}
+	for name, db := range map[string]*Db{
+		"users":           GetDb(masterDB).Tab("users"),
+		"corm_demo.users": GetDb(masterDB).Tab("corm_demo.users u"),
+	} {
+		if schema, table, ok := db.plainTable(); !ok || table != "users" || (schema != "") != strings.Contains(name, ".") {
+			t.Fatalf("%s 解析为 %q.%q", name, schema, table)
+		}
+	}
+	if _, _, ok := GetDb(masterDB).Tab("users").Where("age", ">", 0).plainTable(); ok {
+		t.Fatal("有条件时不能使用 information_schema 估算")
+	}
+	for k, v := range data {
+		fmt.Println(k, v.Name, v.Age)
+	}
+}
+
+func TestChunk(t *testing.T) {
+	fmt.Println("------------------- 分块遍历 -------------------")
+	err := GetDb(masterDB).Tab("users").Select("id", "name").ChunkByID("id", 2, func(rows *sql.Rows) error {
+		fmt.Println("----- 分块 -----")
+		for rows.Next() {
+			var id int64
+			var name string
+			if err := rows.Scan(&id, &name); err != nil {
+				return err
+			}
+			fmt.Println(id, name)
+		}
+		return nil
+	})
+	retErr(err)
+
+	err = GetDb(masterDB).Tab("users").Select("id").Where("age", ">", 0).ChunkTx(2, func(dbTrans *Db, rows *sql.Rows) error {
+		var ids []interface{}
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		count, err := dbTrans.Tab("users").WhereIn("id", ids...).Count()
+		fmt.Println("事务中统计当前块：", count)
+		return err
+	})
+	retErr(err)
+
+	//OR 条件需与主键范围分组，否则每块都会返回全部满足 age > 20 的数据
+	total, err := GetDb(masterDB).Tab("users").Where("age", ">", 20).OrWhere("id", "<", 3).Count()
+	retErr(err)
+	seen := make(map[int64]bool)
+	err = GetDb(masterDB).Tab("users").Select("id").Where("age", ">", 20).OrWhere("id", "<", 3).Chunk(2, func(rows *sql.Rows) error {
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				return err
+			}
+			if seen[id] {
+				return fmt.Errorf("重复的记录 %d", id)
+			}
+			seen[id] = true
+		}
+		return nil
+	})
+	retErr(err)
+	if int64(len(seen)) != total {
+		t.Fatalf("分块遍历 %d 条，应为 %d", len(seen), total)
+	}
+}
+
+func TestRows(t *testing.T) {
+	fmt.Println("------------------- 迭代器 -------------------")
+	for rows, err := range GetDb(masterDB).Tab("users").Select("name", "age").OrderBy("id", "asc").Rows() {
+		retErr(err)
+		user := new(Users)
+		retErr(rows.Scan(&user.Name, &user.Age))
+		fmt.Println(user.Name, user.Age)
+		if user.Age > 20 {
+			break
+		}
+	}
+
+	list, err := All(GetDb(masterDB).Tab("users").Select("name", "age"), func(rows *sql.Rows) (*Users, error) {
+		user := new(Users)
+		err := rows.Scan(&user.Name, &user.Age)
+		return user, err
+	})
+	retErr(err)
+	for k, v := range list {
+		fmt.Println(k, v.Name, v.Age)
+	}
+}
+
+func TestFind(t *testing.T) {
+	fmt.Println("------------------- 生成的扫描代码 -------------------")
+	//Users 包含时间字段，需设置 parseTime=true
+	conn, err := sql.Open("mysql", "root:g2q3g5p8@tcp(127.0.0.1:3306)/corm_demo?parseTime=true")
+	retErr(err)
+	defer conn.Close()
+
+	var list UsersList
+	retErr(GetDb(conn).Tab(UsersTable).Where("age", ">", 20).OrderBy("id", "asc").Find(&list))
+	for k, v := range list {
+		fmt.Println(k, v.Id, v.Name, v.Age, v.CreatedAt)
+	}
+
+	user := new(Users)
+	retErr(GetDb(conn).Tab(UsersTable).OrderBy("id", "asc").FindOne(user))
+	fmt.Println(user.Id, user.Name, user.InsertMap())
+
+	//Select 的字段与 Columns() 一致时可带表名、别名
+	user = new(Users)
+	retErr(GetDb(conn).Tab(UsersTable+" u").Select("u.id", "u.name", "u.age", "u.phone", "u.created_at", "u.updated_at AS updated_at").
+		OrderBy("u.id", "asc").FindOne(user))
+	//字段不同或顺序不同时返回错误，避免扫描到错误的字段
+	list = nil
+	if err := GetDb(conn).Tab(UsersTable).Select("name", "id").Find(&list); err == nil || !strings.Contains(err.Error(), "Find:") {
+		t.Fatalf("查询字段与 Columns() 不一致时应返回错误：%v", err)
+	}
+	if err := GetDb(conn).Tab(UsersTable).Select("name", "id", "age", "phone", "created_at", "updated_at").FindOne(user); err == nil || !strings.Contains(err.Error(), "FindOne:") {
+		t.Fatalf("查询字段顺序与 Columns() 不一致时应返回错误：%v", err)
+	}
+}
+
 func TestCount(t *testing.T) {
 	fmt.Println("------------------- Count -------------------")
 	count, err := GetDb(masterDB).Tab("users").Where("age", ">", 20).Count()
@@ -268,6 +889,99 @@ func TestTrans(t *testing.T) {
 	retErr(err)
 }
 
+func TestTransRetry(t *testing.T) {
+	fmt.Println("------------------- 事务重试 -------------------")
+	err := GetDb(masterDB).Retry(DefaultRetryPolicy()).Transaction(func(dbTrans *Db) error {
+		fmt.Println("第", dbTrans.Attempt(), "次执行")
+		_, err := dbTrans.Tab("users").WhereEqual("nickname", "夏雨荷").Update(map[string]interface{}{
+			"age": 31,
+		})
+		return err
+	})
+	retErr(err)
+}
+
+func TestLock(t *testing.T) {
+	fmt.Println("------------------- 行锁 -------------------")
+	err := GetDb(masterDB).Transaction(func(dbTrans *Db) error {
+		var age int64
+		err := dbTrans.Tab("users").Select("age").WhereEqual("nickname", "夏雨荷").LockForUpdate().First(&age)
+		if err != nil {
+			return err
+		}
+		_, err = dbTrans.Tab("users").WhereEqual("nickname", "夏雨荷").Update(map[string]interface{}{
+			"age": age + 1,
+		})
+		return err
+	})
+	retErr(err)
+
+	_, err = GetDb(masterDB).Tab("users").LockForUpdate().Count()
+	fmt.Println("事务外使用行锁：", err)
+
+	//统计、聚合查询不能丢失行锁
+	for name, sqlStr := range map[string]string{
+		"Count":      GetDb(masterDB).Tab("users").Where("age", ">", 20).LockForUpdate().countToSql(),
+		"GroupBy":    GetDb(masterDB).Tab("users").GroupBy("age").LockForUpdate().countToSql(),
+		"Sum":        GetDb(masterDB).Tab("users").LockForUpdate().sumToSql(),
+		"Avg":        GetDb(masterDB).Tab("users").LockForShare().aggregateToSql("AVG(`age`)"),
+		"SkipLocked": GetDb(masterDB).Tab("users").LockForUpdate().SkipLocked().maxToSql(),
+	} {
+		fmt.Println(name, sqlStr)
+		if !strings.Contains(sqlStr, " FOR ") {
+			t.Fatalf("%s 缺少锁：%s", name, sqlStr)
+		}
+	}
+}
+
+func TestTransHook(t *testing.T) {
+	fmt.Println("------------------- 事务回调 -------------------")
+	err := GetDb(masterDB).Transaction(func(dbTrans *Db) error {
+		dbTrans.AfterCommit(func() {
+			fmt.Println("外层事务已提交")
+		})
+		_ = dbTrans.Transaction(func(inner *Db) error {
+			inner.AfterCommit(func() {
+				fmt.Println("不会执行：保存点已回滚")
+			})
+			inner.AfterRollback(func() {
+				fmt.Println("保存点已回滚")
+			})
+			return errors.New("回滚保存点")
+		})
+		return dbTrans.Transaction(func(inner *Db) error {
+			inner.AfterCommit(func() {
+				fmt.Println("嵌套事务随外层事务提交")
+			})
+			_, err := inner.Tab("users").WhereEqual("nickname", "夏雨荷").Update(map[string]interface{}{
+				"age": 32,
+			})
+			return err
+		})
+	})
+	retErr(err)
+}
+
+func TestTransPanic(t *testing.T) {
+	fmt.Println("------------------- 事务 panic -------------------")
+	db := GetDb(masterDB)
+	rolledBack := false
+	func() {
+		defer func() {
+			fmt.Println("recover：", recover())
+		}()
+		_ = db.Transaction(func(dbTrans *Db) error {
+			dbTrans.AfterRollback(func() {
+				rolledBack = true
+			})
+			panic("回调 panic")
+		})
+	}()
+	if !rolledBack || db.tx != nil || db.scope != nil {
+		t.Fatal("panic 后应回滚事务并退出事务")
+	}
+}
+
 func TestForce(t *testing.T) {
 	fmt.Println("------------------- 强制索引 -------------------")
 	name, phone := "", ""
@@ -309,6 +1023,102 @@ func TestValue(t *testing.T) {
 	fmt.Println("float值：", valFloat)
 }
 
+func TestErrors(t *testing.T) {
+	fmt.Println("------------------- 错误类型 -------------------")
+	notFound := translateErr(sql.ErrNoRows)
+	if !errors.Is(notFound, ErrNotFound) || !errors.Is(notFound, sql.ErrNoRows) {
+		t.Fatalf("记录不存在应为 ErrNotFound 并保留 sql.ErrNoRows：%v", notFound)
+	}
+	for number, kind := range map[uint16]error{
+		1062: ErrDuplicateKey,
+		1452: ErrForeignKey,
+		1213: ErrDeadlock,
+		1205: ErrLockTimeout,
+		1406: ErrDataTooLong,
+	} {
+		err := translateErr(fmt.Errorf("exec: %w", &mysql.MySQLError{Number: number, Message: "test"}))
+		var mysqlErr *mysql.MySQLError
+		if !errors.Is(err, kind) || !errors.As(err, &mysqlErr) || mysqlErr.Number != number {
+			t.Fatalf("错误码 %d 应为 %v：%v", number, kind, err)
+		}
+		fmt.Println(number, err)
+	}
+	if err := translateErr(&mysql.MySQLError{Number: 1064}); errors.Is(err, ErrDuplicateKey) || errors.Is(err, ErrNotFound) {
+		t.Fatalf("未知错误码不应转换：%v", err)
+	}
+
+	SetCompatErrors(true)
+	compat := errs(notFound)
+	SetCompatErrors(false)
+	if compat != nil {
+		t.Fatalf("兼容模式应忽略记录不存在：%v", compat)
+	}
+	if errs(notFound) == nil {
+		t.Fatal("非兼容模式应返回记录不存在")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	fmt.Println("------------------- 条件校验 -------------------")
+	//校验在执行语句前完成，不需要数据库中的数据
+	_, err := GetDb(masterDB).Tab("users").
+		WhereNotIn("id").
+		Where("age", "==", 20).
+		OrderBy("id", "").
+		Limit(-1).
+		Count()
+	fmt.Println(err)
+	for _, want := range []string{"WhereNotIn:", "Where:", "OrderBy:", "Limit:"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("缺少 %s 的校验错误：%v", want, err)
+		}
+	}
+
+	//In 条件为空时不能被忽略，否则会修改、删除全部数据
+	_, err = GetDb(masterDB).Tab("users").WhereIn("id").Update(map[string]interface{}{"age": 1})
+	if err == nil || !strings.Contains(err.Error(), "WhereIn:") {
+		t.Fatalf("WhereIn 条件为空时应返回错误：%v", err)
+	}
+	_, err = GetDb(masterDB).Tab("users").WhereInInt64("id").Delete()
+	if err == nil || !strings.Contains(err.Error(), "WhereInInt64:") {
+		t.Fatalf("WhereInInt64 条件为空时应返回错误：%v", err)
+	}
+	var ids []string
+	_, err = GetDb(masterDB).Tab("users").WhereInStr("phone", ids...).Count()
+	if err == nil || !strings.Contains(err.Error(), "WhereInStr:") {
+		t.Fatalf("WhereInStr 条件为空时应返回错误：%v", err)
+	}
+}
+
+//按位置编号占位符的方言
+type numberedDialect struct {
+	MySQL
+}
+
+func (numberedDialect) Placeholder(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+func TestDialect(t *testing.T) {
+	fmt.Println("------------------- 方言占位符 -------------------")
+	SetDialect(numberedDialect{})
+	defer SetDialect(MySQL{})
+
+	sqlStr := GetDb(masterDB).Tab("users").
+		SelectExpr(IfNull(Col("phone"), "-"), "phone").
+		Where("age", ">", 20).
+		WhereRaw("name <> '?' AND id IN (:ids)", map[string]interface{}{"ids": []int{9, 10}}).
+		PrintSql()
+	fmt.Println(sqlStr)
+	if !strings.Contains(sqlStr, "IFNULL(`phone`, $1)") || !strings.Contains(sqlStr, "`age` > $2") ||
+		!strings.Contains(sqlStr, "name <> '?' AND id IN ($3, $4)") {
+		t.Fatalf("占位符应按位置编号：%s", sqlStr)
+	}
+	if n := countPlaceholders("SELECT ? FROM t WHERE a = '?' AND b = ?"); n != 2 {
+		t.Fatalf("占位符数量 %d，应为 2", n)
+	}
+}
+
 func retErr(err error) {
 	if err != nil {
 		panic(err)
@@ -38,26 +38,69 @@ func (db *Db) Tab(table string) *Db {
 	newDB := dbPool.Get().(*Db)
 	newDB.conn = db.conn
 	newDB.tx = db.tx
-	newDB.table = table
+	newDB.scope = db.scope
+	newDB.table = newDB.quoteTable("Tab", table)
 	return newDB
 }
 
 /**
-设置查询字段，格式：Select("id", "name", "age")
-field 查询字段
+使用子查询作为数据表，格式：TabSub(GetDb(conn).Tab("orders").Select("user_id").GroupBy("user_id"), "t")
+sub 子查询
+alias 别名
+*/
+func (db *Db) TabSub(sub *Db, alias string) *Db {
+	newDB := db.Tab("")
+	quoted, ok := quoteIdent(alias)
+	if !ok {
+		newDB.pushErr(methodErr("TabSub", "非法别名 %q", alias))
+	}
+	newDB.table = quoted
+	newDB.tabSub = sub
+	return newDB
+}
+
+/**
+设置查询字段，格式：Select("id", "u.name", "age AS user_age")
+field 查询字段，会被引用并校验，表达式请使用 SelectRaw
 */
 func (db *Db) Select(field ...string) *Db {
-	db.fields = append(db.fields, field...)
+	for _, f := range field {
+		db.fields = append(db.fields, fieldExpr(db.quoteColumn("Select", f), nil))
+	}
 	return db
 }
 
 /**
-设置查询字段原生格式，格式：Select("id, name, age, IFNULL(sex=1,1,2) AS sex")
+标量子查询作为查询字段，格式：SelectSub(GetDb(conn).Tab("orders o").SelectRaw("COUNT(*)").WhereColumn("o.user_id", "=", "u.id"), "order_count")
+sub 子查询，只能返回一行一列
+alias 别名
+*/
+func (db *Db) SelectSub(sub *Db, alias string) *Db {
+	quoted, ok := quoteIdent(alias)
+	if !ok {
+		db.pushErr(methodErr("SelectSub", "非法别名 %q", alias))
+	}
+	db.fields = append(db.fields, field{expr: quoted, sub: sub})
+	return db
+}
+
+/**
+设置查询字段原生格式，格式：SelectRaw("id, name, age")、SelectRaw("IFNULL(sex=1,1,2) AS sex")、
+SelectRaw("IF(age > ?, 1, 0) AS adult", 18)、SelectRaw("IF(age > :age, 1, 0) AS adult", map[string]interface{}{"age": 18})
+表达式原样保留，参数排在 where 参数之前
 field 查询字段
+args 占位符对应的参数，传入 map[string]interface{} 时使用 :name 命名参数
 */
-func (db *Db) SelectRaw(field string) *Db {
-	fieldTmp := strings.Split(field, ",")
-	db.fields = append(db.fields, fieldTmp...)
+func (db *Db) SelectRaw(field string, args ...interface{}) *Db {
+	if strings.TrimSpace(field) == "" {
+		db.pushErr(methodErr("SelectRaw", "查询字段不能为空"))
+		return db
+	}
+	expr, vals := db.bindArgs("SelectRaw", field, args)
+	if n := countPlaceholders(expr); n != len(vals) {
+		db.pushErr(methodErr("SelectRaw", "占位符数量 %d 与参数数量 %d 不一致", n, len(vals)))
+	}
+	db.fields = append(db.fields, fieldExpr(strings.TrimSpace(expr), vals))
 	return db
 }
 
@@ -65,16 +108,10 @@ func (db *Db) SelectRaw(field string) *Db {
 查询条件，格式：Where("id", ">", 100).where("name", "=", "张三")
 field 查询字段
 operator 条件符号 >、<、=、<>、like、in 等
-condition 条件值
+condition 条件值，为 nil 或无效的 sql.Null* 时，= 生成 IS NULL，<>、!= 生成 IS NOT NULL
 */
 func (db *Db) Where(field, operator string, condition interface{}) *Db {
-
-	db.where = append(db.where, where{
-		field:     field,
-		operator:  operator,
-		condition: condition,
-	})
-	return db
+	return db.pushWhere("Where", field, operator, condition)
 }
 
 /**
@@ -101,7 +138,7 @@ func (db *Db) WhereNil(field, operator string, condition interface{}) *Db {
 等于
 */
 func (db *Db) WhereEqual(field string, condition interface{}) *Db {
-	return db.Where(field, "=", condition)
+	return db.pushWhere("WhereEqual", field, "=", condition)
 }
 
 /*
@@ -133,7 +170,7 @@ func (db *Db) WhereStrToInt(field, operator string, condition string) *Db {
 	if err != nil {
 		db.pushErr(err)
 	}
-	return db.Where(field, operator, strInt)
+	return db.pushWhere("WhereStrToInt", field, operator, strInt)
 }
 
 /**
@@ -158,7 +195,7 @@ func (db *Db) WhereStrToIntNil(field, operator string, condition string) *Db {
 */
 func (db *Db) WhereInt64ToStr(field, operator string, condition int64) *Db {
 	intStr := strconv.FormatInt(condition, 10)
-	return db.Where(field, operator, intStr)
+	return db.pushWhere("WhereInt64ToStr", field, operator, intStr)
 }
 
 /**
@@ -183,7 +220,7 @@ func (db *Db) WhereInt64ToStrNil(field, operator string, condition int64) *Db {
 */
 func (db *Db) WhereIntToStr(field, operator string, condition int) *Db {
 	intStr := strconv.Itoa(condition)
-	return db.Where(field, operator, intStr)
+	return db.pushWhere("WhereIntToStr", field, operator, intStr)
 }
 
 /**
@@ -204,27 +241,25 @@ func (db *Db) WhereIntToStrNil(field, operator string, condition int) *Db {
 }
 
 /**
-查询条件原生格式，格式：Where("id > 100 and name = '张三'")
-where 条件字符串
+查询条件原生格式，格式：WhereRaw("id > ? and name = ?", 100, "张三")、WhereRaw("id IN (:ids)", map[string]interface{}{"ids": ids})
+where 条件字符串，用户输入必须通过占位符绑定
+args 占位符对应的参数，传入 map[string]interface{} 时使用 :name 命名参数，切片展开为多个占位符
 */
-func (db *Db) WhereRaw(where string) *Db {
-	db.whereRaw = append(db.whereRaw, where)
-	return db
+func (db *Db) WhereRaw(where string, args ...interface{}) *Db {
+	return db.pushWhereRaw("WhereRaw", where, args)
 }
 
 /**
 查询 In 条件，格式：WhereIn("name", "张")
-where 条件字符串
+where 条件字符串，为空时返回错误，避免条件被忽略后查询、修改、删除全部数据
 */
 func (db *Db) WhereIn(field string, condition ...interface{}) *Db {
-	if len(condition) == 0 {
-		return db
-	}
 	if checkWhereIn(condition) {
 		db.pushErr(errors.New("WhereIn 参数类型错误"))
 	}
 	db.where = append(db.where, where{
-		field:          field,
+		method:         "WhereIn",
+		field:          db.quoteColumn("WhereIn", field),
 		operator:       IN,
 		conditionArray: condition,
 	})
@@ -233,19 +268,17 @@ func (db *Db) WhereIn(field string, condition ...interface{}) *Db {
 
 /**
 查询 In 条件，格式：WhereIn("name", "张")
-where 条件字符串
+where 条件字符串，为空时返回错误，避免条件被忽略后查询、修改、删除全部数据
 */
 func (db *Db) WhereInStr(field string, val ...string) *Db {
 	length := len(val)
-	if length == 0 {
-		return db
-	}
 	condition := make([]interface{}, 0, 5)
 	for i := 0; i < length; i++ {
 		condition = append(condition, val[i])
 	}
 	db.where = append(db.where, where{
-		field:          field,
+		method:         "WhereInStr",
+		field:          db.quoteColumn("WhereInStr", field),
 		operator:       IN,
 		conditionArray: condition,
 	})
@@ -254,19 +287,17 @@ func (db *Db) WhereInStr(field string, val ...string) *Db {
 
 /**
 查询 In 条件，格式：WhereIn("name", "张")
-where 条件字符串
+where 条件字符串，为空时返回错误，避免条件被忽略后查询、修改、删除全部数据
 */
 func (db *Db) WhereInInt(field string, val ...int) *Db {
 	length := len(val)
-	if length == 0 {
-		return db
-	}
 	condition := make([]interface{}, 0, 5)
 	for i := 0; i < length; i++ {
 		condition = append(condition, val[i])
 	}
 	db.where = append(db.where, where{
-		field:          field,
+		method:         "WhereInInt",
+		field:          db.quoteColumn("WhereInInt", field),
 		operator:       IN,
 		conditionArray: condition,
 	})
@@ -275,19 +306,17 @@ func (db *Db) WhereInInt(field string, val ...int) *Db {
 
 /**
 查询 In 条件，格式：WhereIn("name", "张")
-where 条件字符串
+where 条件字符串，为空时返回错误，避免条件被忽略后查询、修改、删除全部数据
 */
 func (db *Db) WhereInInt64(field string, val ...int64) *Db {
 	length := len(val)
-	if length == 0 {
-		return db
-	}
 	condition := make([]interface{}, 0, 5)
 	for i := 0; i < length; i++ {
 		condition = append(condition, val[i])
 	}
 	db.where = append(db.where, where{
-		field:          field,
+		method:         "WhereInInt64",
+		field:          db.quoteColumn("WhereInInt64", field),
 		operator:       IN,
 		conditionArray: condition,
 	})
@@ -303,13 +332,48 @@ func (db *Db) WhereNotIn(field string, condition ...interface{}) *Db {
 		db.pushErr(errors.New("WhereNotIn 参数类型错误"))
 	}
 	db.where = append(db.where, where{
-		field:          field,
+		method:         "WhereNotIn",
+		field:          db.quoteColumn("WhereNotIn", field),
 		operator:       NOT_IN,
 		conditionArray: condition,
 	})
 	return db
 }
 
+/**
+查询 In 子查询条件，格式：WhereInSub("id", GetDb(conn).Tab("user_groups").Select("user_id").Where("group_id", "=", 1))
+field 查询字段
+sub 子查询
+*/
+func (db *Db) WhereInSub(field string, sub *Db) *Db {
+	return db.pushWhereSub("WhereInSub", field, IN, sub)
+}
+
+/**
+查询 Not In 子查询条件，格式：WhereNotInSub("id", sub)
+field 查询字段
+sub 子查询
+*/
+func (db *Db) WhereNotInSub(field string, sub *Db) *Db {
+	return db.pushWhereSub("WhereNotInSub", field, NOT_IN, sub)
+}
+
+/**
+Exists 子查询条件，格式：WhereExists(GetDb(conn).Tab("orders o").WhereColumn("o.user_id", "=", "u.id"))
+sub 子查询
+*/
+func (db *Db) WhereExists(sub *Db) *Db {
+	return db.pushWhereSub("WhereExists", "", EXISTS, sub)
+}
+
+/**
+Not Exists 子查询条件，格式：WhereNotExists(sub)
+sub 子查询
+*/
+func (db *Db) WhereNotExists(sub *Db) *Db {
+	return db.pushWhereSub("WhereNotExists", "", NOT_EXISTS, sub)
+}
+
 /**
 查询 like 条件，格式：WhereLike("name", "张")
 where 条件字符串
@@ -317,7 +381,8 @@ where 条件字符串
 func (db *Db) WhereLike(field string, condition string) *Db {
 	condition = "%" + condition + "%"
 	db.where = append(db.where, where{
-		field:     field,
+		method:    "WhereLike",
+		field:     db.quoteColumn("WhereLike", field),
 		operator:  LIKE,
 		condition: condition,
 	})
@@ -349,7 +414,8 @@ where 条件字符串
 func (db *Db) WhereLikeLeft(field string, condition string) *Db {
 	condition = condition + "%"
 	db.where = append(db.where, where{
-		field:     field,
+		method:    "WhereLikeLeft",
+		field:     db.quoteColumn("WhereLikeLeft", field),
 		operator:  LIKE,
 		condition: condition,
 	})
@@ -381,7 +447,8 @@ where 条件字符串
 func (db *Db) WhereNotLike(field string, condition string) *Db {
 	condition = "%" + condition + "%"
 	db.where = append(db.where, where{
-		field:     field,
+		method:    "WhereNotLike",
+		field:     db.quoteColumn("WhereNotLike", field),
 		operator:  NOT_LIKE,
 		condition: condition,
 	})
@@ -412,33 +479,136 @@ where 条件字符串
 */
 func (db *Db) WhereBetween(field string, startCondition interface{}, endCondition interface{}) *Db {
 	db.where = append(db.where, where{
-		field:          field,
+		method:         "WhereBetween",
+		field:          db.quoteColumn("WhereBetween", field),
 		operator:       BETWEEN,
 		conditionArray: []interface{}{startCondition, endCondition},
 	})
 	return db
 }
 
+/**
+字段为 NULL，格式：WhereNull("deleted_at")
+*/
+func (db *Db) WhereNull(field string) *Db {
+	return db.pushWhere("WhereNull", field, IS_NULL, nil)
+}
+
+/**
+字段不为 NULL，格式：WhereNotNull("deleted_at")
+*/
+func (db *Db) WhereNotNull(field string) *Db {
+	return db.pushWhere("WhereNotNull", field, NOT_NULL, nil)
+}
+
+/**
+NULL 安全的等于（<=>），条件值为 NULL 时匹配 NULL，格式：WhereNullSafe("parent_id", parentId)
+*/
+func (db *Db) WhereNullSafe(field string, condition interface{}) *Db {
+	return db.pushWhere("WhereNullSafe", field, NULL_SAFE, condition)
+}
+
+/**
+或条件，格式：Where("age", ">", 18).OrWhere("name", "=", "张三")
+field 查询字段
+operator 条件符号
+condition 条件值
+*/
+func (db *Db) OrWhere(field, operator string, condition interface{}) *Db {
+	db.pushWhere("OrWhere", field, operator, condition)
+	db.where[len(db.where)-1].or = true
+	return db
+}
+
+/**
+字段与字段比较，两边都作为字段名引用，格式：WhereColumn("orders.money", ">", "orders.total")
+left 左侧字段
+operator 条件符号 =、<>、!=、>、>=、<、<=
+right 右侧字段
+*/
+func (db *Db) WhereColumn(left, operator, right string) *Db {
+	db.where = append(db.where, where{
+		method:   "WhereColumn",
+		field:    db.quoteColumn("WhereColumn", left),
+		operator: normalizeOperator(operator),
+		column:   db.quoteColumn("WhereColumn", right),
+	})
+	return db
+}
+
+/**
+或条件：字段与字段比较，格式：WhereColumn("a.id", "=", "b.a_id").OrWhereColumn("a.id", "=", "b.parent_id")
+*/
+func (db *Db) OrWhereColumn(left, operator, right string) *Db {
+	db.WhereColumn(left, operator, right)
+	db.where[len(db.where)-1].method = "OrWhereColumn"
+	db.where[len(db.where)-1].or = true
+	return db
+}
+
+/**
+分组条件，回调中的条件用括号包裹，格式：WhereGroup(func(q *Db) { q.Where("age", ">", 18).OrWhere("vip", "=", 1) })
+callable 回调函数，在 q 上添加条件
+*/
+func (db *Db) WhereGroup(callable func(q *Db)) *Db {
+	return db.pushWhereGroup("WhereGroup", callable, false)
+}
+
+/**
+或分组条件，格式：Where("status", "=", 1).OrWhereGroup(func(q *Db) {...})
+callable 回调函数，在 q 上添加条件
+*/
+func (db *Db) OrWhereGroup(callable func(q *Db)) *Db {
+	return db.pushWhereGroup("OrWhereGroup", callable, true)
+}
+
 /*
 强制索引
 index 索引名称
 */
 func (db *Db) Force(index string) *Db {
 	if index != "" {
-		db.force = "FORCE INDEX(`" + index + "`)"
+		quoted, ok := quoteIdent(index)
+		if !ok {
+			db.pushErr(methodErr("Force", "非法索引名 %q", index))
+		}
+		db.force = "FORCE INDEX(" + quoted + ")"
 	}
 	return db
 }
 
+/**
+去重查询 SELECT DISTINCT，Count、GetPage 统计去重后的行数
+*/
+func (db *Db) Distinct() *Db {
+	db.distinct = true
+	return db
+}
+
 /**
 查询结果过滤 Having ，格式：Having("name", "=", "张三").Having("age", ">", 18)
 Having 条件字符串
 */
 func (db *Db) Having(field, operator string, condition interface{}) *Db {
-	db.having = append(db.having, having{
-		field:     field,
-		operator:  operator,
-		condition: condition,
+	db.having = append(db.having, db.newWhere("Having", field, operator, condition))
+	return db
+}
+
+/**
+原生分组过滤条件，格式：HavingRaw("COUNT(*) > ?", 10)、HavingRaw("SUM(amount) >= :min", map[string]interface{}{"min": 100})
+having 条件语句
+args 占位符对应的参数，传入 map[string]interface{} 时使用 :name 命名参数
+*/
+func (db *Db) HavingRaw(having string, args ...interface{}) *Db {
+	if strings.TrimSpace(having) == "" {
+		db.pushErr(methodErr("HavingRaw", "条件不能为空"))
+		return db
+	}
+	having, args = db.bindArgs("HavingRaw", having, args)
+	db.having = append(db.having, where{
+		method:         "HavingRaw",
+		raw:            having,
+		conditionArray: args,
 	})
 	return db
 }
@@ -446,12 +616,25 @@ func (db *Db) Having(field, operator string, condition interface{}) *Db {
 /**
 排序，格式：OrderBy("id", "desc").OrderBy("name", "asc")
 field 字段
-by asc或desc
+by asc或desc，其他值会返回错误
 */
 func (db *Db) OrderBy(field, by string) *Db {
 	db.orderBy = append(db.orderBy, orderBy{
-		field: field,
-		by:    by,
+		method: "OrderBy",
+		field:  db.quoteColumn("OrderBy", field),
+		by:     strings.ToUpper(strings.TrimSpace(by)),
+	})
+	return db
+}
+
+/**
+原生排序，格式：OrderByRaw("FIELD(status, 2, 1, 3)")
+expr 排序表达式，原样拼接，不能包含用户输入
+*/
+func (db *Db) OrderByRaw(expr string) *Db {
+	db.orderBy = append(db.orderBy, orderBy{
+		method: "OrderByRaw",
+		field:  expr,
 	})
 	return db
 }
@@ -462,7 +645,9 @@ field 字段
 by asc或desc
 */
 func (db *Db) GroupBy(field ...string) *Db {
-	db.groupBy = append(db.groupBy, field...)
+	for _, f := range field {
+		db.groupBy = append(db.groupBy, db.quoteColumn("GroupBy", f))
+	}
 	return db
 }
 
@@ -491,7 +676,7 @@ on 关联条件
 */
 func (db *Db) LeftJoin(table, on string) *Db {
 	db.join = append(db.join, join{
-		table:     table,
+		table:     db.quoteTable("LeftJoin", table),
 		direction: LEFT_JOIN,
 		on:        on,
 	})
@@ -505,7 +690,7 @@ on 关联条件
 */
 func (db *Db) RightJoin(table, on string) *Db {
 	db.join = append(db.join, join{
-		table:     table,
+		table:     db.quoteTable("RightJoin", table),
 		direction: RIGHT_JOIN,
 		on:        on,
 	})
@@ -519,13 +704,152 @@ on 关联条件
 */
 func (db *Db) Join(table, on string) *Db {
 	db.join = append(db.join, join{
-		table:     table,
+		table:     db.quoteTable("Join", table),
 		direction: INNER_JOIN,
 		on:        on,
 	})
 	return db
 }
 
+/**
+左连接，关联条件使用构造器，格式：LeftJoinOn("groups g", func(on *Db) { on.WhereColumn("u.group_id", "=", "g.id").Where("g.status", "=", 1) })
+table 表名
+callable 回调函数，在 on 上添加关联条件
+*/
+func (db *Db) LeftJoinOn(table string, callable func(on *Db)) *Db {
+	return db.pushJoinOn("LeftJoinOn", LEFT_JOIN, table, callable)
+}
+
+/**
+右连接，关联条件使用构造器
+table 表名
+callable 回调函数，在 on 上添加关联条件
+*/
+func (db *Db) RightJoinOn(table string, callable func(on *Db)) *Db {
+	return db.pushJoinOn("RightJoinOn", RIGHT_JOIN, table, callable)
+}
+
+/**
+内连接，关联条件使用构造器，格式：JoinOn("user_groups ug", func(on *Db) { on.WhereColumn("u.id", "=", "ug.user_id") })
+table 表名
+callable 回调函数，在 on 上添加关联条件
+*/
+func (db *Db) JoinOn(table string, callable func(on *Db)) *Db {
+	return db.pushJoinOn("JoinOn", INNER_JOIN, table, callable)
+}
+
+/**
+排他锁 SELECT ... FOR UPDATE，只能在事务中使用，格式：
+dbTrans.Tab("accounts").Select("balance").Where("id", "=", 1).LockForUpdate().First(&balance)
+*/
+func (db *Db) LockForUpdate() *Db {
+	db.lock = FOR_UPDATE
+	return db
+}
+
+/**
+共享锁 SELECT ... FOR SHARE，MySQL 5.7 生成 LOCK IN SHARE MODE，只能在事务中使用
+*/
+func (db *Db) LockForShare() *Db {
+	db.lock = FOR_SHARE
+	return db
+}
+
+/**
+跳过已被锁定的行，需配合 LockForUpdate、LockForShare 使用，MySQL 8.0 及以上支持
+*/
+func (db *Db) SkipLocked() *Db {
+	db.wait = SKIP_LOCK
+	return db
+}
+
+/**
+行已被锁定时立即返回错误而不等待，需配合 LockForUpdate、LockForShare 使用，MySQL 8.0 及以上支持
+*/
+func (db *Db) NoWait() *Db {
+	db.wait = NOWAIT
+	return db
+}
+
+/**
+公用表表达式，格式：With("paid", GetDb(conn).Tab("orders").Where("status", "=", 1)).Tab("paid")...
+需在 Tab 之后调用，CTE 的参数排在语句最前
+name CTE 名称
+sub CTE 查询
+*/
+func (db *Db) With(name string, sub *Db) *Db {
+	return db.pushCte("With", name, nil, sub, nil)
+}
+
+/**
+递归公用表表达式，生成 WITH RECURSIVE name(columns) AS (anchor UNION ALL recursive)
+格式：WithRecursive("tree", []string{"id", "parent_id"},
+		GetDb(conn).Tab("categories").Select("id", "parent_id").Where("id", "=", 1),
+		GetDb(conn).Tab("categories c").Select("c.id", "c.parent_id").JoinOn("tree t", func(on *Db) { on.WhereColumn("c.parent_id", "=", "t.id") }))
+name CTE 名称
+columns 字段名，可为空
+anchor 初始查询
+recursive 递归查询
+*/
+func (db *Db) WithRecursive(name string, columns []string, anchor, recursive *Db) *Db {
+	if recursive == nil {
+		db.pushErr(methodErr("WithRecursive", "递归查询不能为空"))
+		return db
+	}
+	return db.pushCte("WithRecursive", name, columns, anchor, recursive)
+}
+
+func (db *Db) pushCte(method, name string, columns []string, sub, recursive *Db) *Db {
+	if sub == nil {
+		db.pushErr(methodErr(method, "查询不能为空"))
+		return db
+	}
+	quoted, ok := quoteIdent(name)
+	if !ok {
+		db.pushErr(methodErr(method, "非法名称 %q", name))
+	}
+	c := cte{name: quoted, sub: sub, recursive: recursive}
+	for _, column := range columns {
+		quotedColumn, ok := quoteIdent(column)
+		if !ok {
+			db.pushErr(methodErr(method, "非法字段名 %q", column))
+		}
+		c.columns = append(c.columns, quotedColumn)
+	}
+	db.ctes = append(db.ctes, c)
+	return db
+}
+
+/**
+联合查询（去重），格式：Tab("orders").Select("id", "money").Union(GetDb(conn).Tab("orders_archive").Select("id", "money")).OrderBy("id", "desc").Limit(10)
+之前调用的 OrderBy、Limit、Offset 作用于基础查询（如每个查询各取前 N 条），之后调用的作用于联合后的结果
+other 联合的查询
+*/
+func (db *Db) Union(other *Db) *Db {
+	return db.pushUnion("Union", other, false)
+}
+
+/**
+联合查询（不去重），格式同 Union
+other 联合的查询
+*/
+func (db *Db) UnionAll(other *Db) *Db {
+	return db.pushUnion("UnionAll", other, true)
+}
+
+func (db *Db) pushUnion(method string, other *Db, all bool) *Db {
+	if other == nil {
+		db.pushErr(methodErr(method, "联合查询不能为空"))
+		return db
+	}
+	if len(db.unions) == 0 {
+		db.base = unionBase{orderBy: db.orderBy, limit: db.limit, offset: db.offset}
+		db.orderBy, db.limit, db.offset = nil, 0, 0
+	}
+	db.unions = append(db.unions, union{all: all, db: other})
+	return db
+}
+
 /**
 查询一条数据
 callable 回调函数
@@ -612,7 +936,7 @@ func (db *Db) Get(callable func(rows *sql.Rows)) error {
 	for rows.Next() {
 		callable(rows)
 	}
-	return nil
+	return translateErr(rows.Err())
 }
 
 /**
@@ -632,7 +956,7 @@ func (db *Db) Query(callable func(row *sql.Rows) error) (err error) {
 			return err
 		}
 	}
-	return
+	return translateErr(rows.Err())
 }
 
 /**
@@ -690,7 +1014,7 @@ func (db *Db) QueryPage(page, pageCount int, callable func(row *sql.Rows) error)
 Sum
 */
 func (db *Db) Sum(sumField string) (float64, error) {
-	db.sum = sumField
+	db.sum = db.quoteColumn("Sum", sumField)
 	var sum sql.NullFloat64
 	err := db.queryRow(db.sumToSql(), db.getWhereValue(), &sum)
 	if errs(err) != nil {
@@ -703,7 +1027,7 @@ func (db *Db) Sum(sumField string) (float64, error) {
 Sum
 */
 func (db *Db) Max(maxField string) (int64, error) {
-	db.max = maxField
+	db.max = db.quoteColumn("Max", maxField)
 	var max sql.NullInt64
 	err := db.queryRow(db.maxToSql(), db.getWhereValue(), &max)
 	if errs(err) != nil {
@@ -716,7 +1040,7 @@ func (db *Db) Max(maxField string) (int64, error) {
 Sum
 */
 func (db *Db) Min(minField string) (int64, error) {
-	db.min = minField
+	db.min = db.quoteColumn("Min", minField)
 	var min sql.NullInt64
 	err := db.queryRow(db.minToSql(), db.getWhereValue(), &min)
 	if errs(err) != nil {
@@ -726,7 +1050,7 @@ func (db *Db) Min(minField string) (int64, error) {
 }
 
 /**
-Count
+Count，分组、去重、联合查询统计结果行数：SELECT COUNT(*) FROM (子查询) AS t
 */
 func (db *Db) Count() (int64, error) {
 	var count sql.NullInt64
@@ -738,6 +1062,15 @@ func (db *Db) Count() (int64, error) {
 	return count.Int64, nil
 }
 
+/**
+去重统计，生成 SELECT COUNT(DISTINCT field)，格式：CountDistinct("user_id")
+不能与 GroupBy、Having、Distinct、Union 同时使用
+*/
+func (db *Db) CountDistinct(field string) (int64, error) {
+	db.count = "COUNT(" + DISTINCT + SPACE + db.quoteColumn("CountDistinct", field) + ")"
+	return db.Count()
+}
+
 /**
 Exists 查询数据是否存在
 */
@@ -754,10 +1087,11 @@ func (db *Db) Exists() (bool, error) {
 }
 
 /**
-打印SQL
+打印SQL，占位符按方言编号
 */
 func (db *Db) PrintSql() string {
-	return db.whereToSql()
+	sqlStr, _ := bindPlaceholders(db.whereToSql())
+	return sqlStr
 }
 
 /**
@@ -785,7 +1119,6 @@ func (db *Db) Update(updateMap map[string]interface{}) (updateNum int64, err err
 	db.update = updateMap
 	updateStr, vals := db.updateToSql()
 
-	vals = append(vals, db.getWhereValue()...)
 	rest, err := db.exec(updateStr, vals...)
 	if err != nil {
 		return 0, err
@@ -797,27 +1130,73 @@ func (db *Db) Update(updateMap map[string]interface{}) (updateNum int64, err err
 	return rows, nil
 }
 
-//执行事务
-func (db *Db) Transaction(callable func(dbTrans *Db) error) error {
-	tx, err := db.conn.Begin()
+/**
+删除数据，必须有 where 条件，删除全部数据请使用 WhereRaw("1 = 1")
+*/
+func (db *Db) Delete() (deleteNum int64, err error) {
+	if len(db.where) == 0 {
+		db.pushErr(methodErr("Delete", "缺少删除条件"))
+	}
+	rest, err := db.exec(db.deleteToSql(), db.getWhereValue()...)
 	if err != nil {
-		return err
+		return 0, err
 	}
-
-	db.tx = tx
-	defer func() {
-		db.tx = nil
-	}()
-	err = callable(db)
+	rows, err := rest.RowsAffected()
 	if err != nil {
-		_ = tx.Rollback()
-		return err
+		return 0, err
 	}
+	return rows, nil
+}
 
-	err = tx.Commit()
-	if err != nil {
-		return err
+//执行事务，在事务中再次调用时使用保存点实现嵌套事务
+func (db *Db) Transaction(callable func(dbTrans *Db) error) error {
+	if db.tx != nil {
+		return db.savepoint(callable)
+	}
+	if db.retry == nil {
+		db.attempt = 1
+		return db.transaction(callable)
 	}
+	return db.retryTransaction(callable)
+}
 
-	return nil
+/**
+设置事务重试策略，格式：Retry(corm.DefaultRetryPolicy()).Transaction(func(dbTrans *Db) error {...})
+policy 重试策略，死锁、锁等待超时等错误时重新开启事务并执行整个回调
+*/
+func (db *Db) Retry(policy RetryPolicy) *Db {
+	db.retry = &policy
+	return db
+}
+
+/**
+当前事务的执行次数，从 1 开始
+*/
+func (db *Db) Attempt() int {
+	return db.attempt
+}
+
+/**
+注册事务提交成功后执行的回调，按注册顺序执行
+嵌套事务中注册的回调在最外层事务提交后执行，所在保存点回滚时丢弃
+不在事务中时立即执行
+*/
+func (db *Db) AfterCommit(callable func()) {
+	if db.scope == nil {
+		callable()
+		return
+	}
+	db.scope.afterCommit = append(db.scope.afterCommit, callable)
+}
+
+/**
+注册事务回滚后执行的回调，按注册顺序执行
+嵌套事务中注册的回调在所在保存点或外层事务回滚后执行
+不在事务中时不会执行
+*/
+func (db *Db) AfterRollback(callable func()) {
+	if db.scope == nil {
+		return
+	}
+	db.scope.afterRollback = append(db.scope.afterRollback, callable)
 }
@@ -0,0 +1,18 @@
+-- 任务队列表，表名可通过 queue.WithTable 修改
+CREATE TABLE IF NOT EXISTS `jobs` (
+  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT,
+  `queue` varchar(64) NOT NULL DEFAULT 'default' COMMENT '队列名',
+  `payload` mediumblob NOT NULL COMMENT '任务数据',
+  `status` varchar(16) NOT NULL DEFAULT 'pending' COMMENT '状态：pending、running、done、failed',
+  `attempts` int(11) NOT NULL DEFAULT '0' COMMENT '已执行次数',
+  `max_attempts` int(11) NOT NULL DEFAULT '5' COMMENT '最大执行次数',
+  `run_at` datetime(6) NOT NULL COMMENT '可执行时间',
+  `locked_by` varchar(128) NOT NULL DEFAULT '' COMMENT '领取任务的 worker',
+  `locked_until` datetime(6) DEFAULT NULL COMMENT '租约到期时间',
+  `last_error` text COMMENT '最后一次错误',
+  `created_at` datetime(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+  `updated_at` datetime(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6),
+  PRIMARY KEY (`id`),
+  KEY `idx_claim` (`queue`, `status`, `run_at`),
+  KEY `idx_reap` (`status`, `locked_until`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,406 @@
+/**
+基于数据库的任务队列，使用 FOR UPDATE SKIP LOCKED 领取任务，需 MySQL 8.0 及以上
+建表语句见 migrations 目录，也可通过 Migrations 读取
+时间字段使用 time.Time，连接串需设置 parseTime=true，各 worker 所在服务器需同步时钟
+*/
+package queue
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/chu108/corm"
+)
+
+//建表语句
+//go:embed migrations/*.sql
+var Migrations embed.FS
+
+//任务状态
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+/**
+租约已失效：任务已被回收或由其他 worker 领取
+*/
+var ErrLeaseLost = errors.New("任务租约已失效")
+
+//查询任务的字段，顺序与 scanJob 一致
+var jobColumns = []string{"id", "queue", "payload", "status", "attempts", "max_attempts", "run_at", "locked_by", "locked_until", "last_error"}
+
+var tableRegexp = regexp.MustCompile("^[A-Za-z_][A-Za-z0-9_]*$")
+
+/**
+任务
+*/
+type Job struct {
+	Id          int64
+	Queue       string
+	Payload     []byte
+	Status      string
+	Attempts    int
+	MaxAttempts int
+	RunAt       time.Time
+	LockedBy    string
+	LockedUntil time.Time
+	LastError   string
+}
+
+/**
+队列
+*/
+type Queue struct {
+	conn        *sql.DB
+	name        string
+	table       string
+	lease       time.Duration
+	maxAttempts int
+	backoff     func(attempt int) time.Duration
+	err         error
+}
+
+type Option func(q *Queue)
+
+/**
+任务表名，默认 jobs
+*/
+func WithTable(table string) Option {
+	return func(q *Queue) {
+		q.table = table
+	}
+}
+
+/**
+租约时长，默认 30 秒，worker 每隔租约的一半自动续期，超时未续期的任务会被 Reap 回收
+*/
+func WithLease(lease time.Duration) Option {
+	return func(q *Queue) {
+		q.lease = lease
+	}
+}
+
+/**
+最大执行次数，默认 5 次
+*/
+func WithMaxAttempts(maxAttempts int) Option {
+	return func(q *Queue) {
+		q.maxAttempts = maxAttempts
+	}
+}
+
+/**
+失败后重新执行的等待时间，attempt 为已执行次数，默认 ExponentialBackoff(10*time.Second, time.Hour)
+*/
+func WithBackoff(backoff func(attempt int) time.Duration) Option {
+	return func(q *Queue) {
+		q.backoff = backoff
+	}
+}
+
+/**
+指数退避，第 n 次失败后等待 base*2^(n-1)，最长 max，并加入随机抖动
+*/
+func ExponentialBackoff(base, max time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		delay := base
+		for i := 1; i < attempt && delay < max; i++ {
+			delay *= 2
+		}
+		if delay > max {
+			delay = max
+		}
+		if delay <= 0 {
+			return 0
+		}
+		return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+	}
+}
+
+/**
+创建队列，格式：queue.New(conn, "emails", queue.WithLease(time.Minute))
+conn 数据库连接
+name 队列名
+*/
+func New(conn *sql.DB, name string, opts ...Option) *Queue {
+	q := &Queue{
+		conn:        conn,
+		name:        name,
+		table:       "jobs",
+		lease:       30 * time.Second,
+		maxAttempts: 5,
+		backoff:     ExponentialBackoff(10*time.Second, time.Hour),
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	switch {
+	case name == "":
+		q.err = errors.New("queue: 队列名不能为空")
+	case !tableRegexp.MatchString(q.table):
+		q.err = fmt.Errorf("queue: 非法表名 %q", q.table)
+	case q.lease <= 0:
+		q.err = fmt.Errorf("queue: 租约时长必须大于 0：%s", q.lease)
+	case q.maxAttempts <= 0:
+		q.err = fmt.Errorf("queue: 最大执行次数必须大于 0：%d", q.maxAttempts)
+	}
+	return q
+}
+
+/**
+添加任务
+payload 任务数据
+runAt 可执行时间，零值表示立即执行
+*/
+func (q *Queue) Enqueue(payload []byte, runAt time.Time) (int64, error) {
+	return q.EnqueueTx(corm.GetDb(q.conn), payload, runAt)
+}
+
+/**
+在事务中添加任务，任务与业务数据一起提交或回滚，格式：
+corm.GetDb(conn).Transaction(func(dbTrans *corm.Db) error { ...; _, err := q.EnqueueTx(dbTrans, payload, time.Time{}); return err })
+*/
+func (q *Queue) EnqueueTx(db *corm.Db, payload []byte, runAt time.Time) (int64, error) {
+	if q.err != nil {
+		return 0, q.err
+	}
+	if runAt.IsZero() {
+		runAt = time.Now()
+	}
+	if payload == nil {
+		payload = []byte{}
+	}
+	return db.Tab(q.table).Insert(map[string]interface{}{
+		"queue":        q.name,
+		"payload":      payload,
+		"status":       StatusPending,
+		"max_attempts": q.maxAttempts,
+		"run_at":       runAt,
+	})
+}
+
+/**
+领取最多 limit 个到期的任务，已被其他 worker 锁定的任务会被跳过
+领取后任务状态为 running，执行次数加 1，需在租约到期前调用 Extend 续期，完成后调用 Done 或 Fail
+worker 领取者标识
+*/
+func (q *Queue) Claim(worker string, limit int) ([]*Job, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	if worker == "" {
+		return nil, errors.New("queue: worker 标识不能为空")
+	}
+	if limit <= 0 {
+		return nil, fmt.Errorf("queue: 领取数量必须大于 0：%d", limit)
+	}
+	var jobs []*Job
+	err := corm.GetDb(q.conn).Transaction(func(dbTrans *corm.Db) error {
+		jobs = jobs[:0]
+		now := time.Now()
+		var scanErr error
+		err := dbTrans.Tab(q.table).Select(jobColumns...).
+			WhereEqual("queue", q.name).
+			WhereEqual("status", StatusPending).
+			Where("run_at", "<=", now).
+			OrderBy("run_at", "asc").
+			OrderBy("id", "asc").
+			Limit(limit).
+			LockForUpdate().
+			SkipLocked().
+			Get(func(rows *sql.Rows) {
+				job, err := scanJob(rows)
+				if err != nil {
+					scanErr = err
+					return
+				}
+				jobs = append(jobs, job)
+			})
+		if err != nil {
+			return err
+		}
+		if scanErr != nil {
+			return scanErr
+		}
+		if len(jobs) == 0 {
+			return nil
+		}
+
+		ids := make([]int64, len(jobs))
+		for k, job := range jobs {
+			ids[k] = job.Id
+		}
+		until := now.Add(q.lease)
+		_, err = dbTrans.Raw("UPDATE `"+q.table+"` SET `status` = :status, `locked_by` = :worker, `locked_until` = :until, `attempts` = `attempts` + 1 WHERE `id` IN (:ids)",
+			map[string]interface{}{
+				"status": StatusRunning,
+				"worker": worker,
+				"until":  until,
+				"ids":    ids,
+			}).Exec()
+		if err != nil {
+			return err
+		}
+		for _, job := range jobs {
+			job.Status = StatusRunning
+			job.Attempts++
+			job.LockedBy = worker
+			job.LockedUntil = until
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+/**
+续期租约，任务已被回收时返回 ErrLeaseLost
+*/
+func (q *Queue) Extend(job *Job) error {
+	until := time.Now().Add(q.lease)
+	err := q.updateLocked(job, map[string]interface{}{
+		"locked_until": until,
+	})
+	if err != nil {
+		return err
+	}
+	job.LockedUntil = until
+	return nil
+}
+
+/**
+任务执行成功
+*/
+func (q *Queue) Done(job *Job) error {
+	err := q.updateLocked(job, map[string]interface{}{
+		"status":       StatusDone,
+		"locked_by":    "",
+		"locked_until": nil,
+	})
+	if err != nil {
+		return err
+	}
+	job.Status = StatusDone
+	return nil
+}
+
+/**
+任务执行失败，未达到最大执行次数时按退避时间重新执行，否则标记为 failed
+cause 失败原因
+*/
+func (q *Queue) Fail(job *Job, cause error) error {
+	values := map[string]interface{}{
+		"locked_by":    "",
+		"locked_until": nil,
+		"last_error":   errorText(cause),
+	}
+	status := StatusFailed
+	if job.Attempts < job.MaxAttempts {
+		status = StatusPending
+		values["run_at"] = time.Now().Add(q.backoff(job.Attempts))
+	}
+	values["status"] = status
+	if err := q.updateLocked(job, values); err != nil {
+		return err
+	}
+	job.Status = status
+	return nil
+}
+
+//更新仍由当前 worker 持有的任务
+//每次领取执行次数都会加 1，同时校验 attempts，租约过期后被同名 worker 重新领取时旧的领取不能再更新任务
+func (q *Queue) updateLocked(job *Job, values map[string]interface{}) error {
+	if q.err != nil {
+		return q.err
+	}
+	n, err := corm.GetDb(q.conn).Tab(q.table).
+		WhereEqual("id", job.Id).
+		WhereEqual("status", StatusRunning).
+		WhereEqual("locked_by", job.LockedBy).
+		WhereEqual("attempts", job.Attempts).
+		Update(values)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+/**
+回收租约已过期的任务（worker 崩溃或失去连接），未达到最大执行次数的任务重新执行，否则标记为 failed
+返回回收的任务数
+*/
+func (q *Queue) Reap() (int64, error) {
+	if q.err != nil {
+		return 0, q.err
+	}
+	now := time.Now()
+	failed, err := corm.GetDb(q.conn).Tab(q.table).
+		WhereEqual("queue", q.name).
+		WhereEqual("status", StatusRunning).
+		Where("locked_until", "<", now).
+		WhereColumn("attempts", ">=", "max_attempts").
+		Update(map[string]interface{}{
+			"status":       StatusFailed,
+			"locked_by":    "",
+			"locked_until": nil,
+			"last_error":   "租约过期",
+		})
+	if err != nil {
+		return 0, err
+	}
+	retried, err := corm.GetDb(q.conn).Tab(q.table).
+		WhereEqual("queue", q.name).
+		WhereEqual("status", StatusRunning).
+		Where("locked_until", "<", now).
+		Update(map[string]interface{}{
+			"status":       StatusPending,
+			"locked_by":    "",
+			"locked_until": nil,
+			"run_at":       now,
+			"last_error":   "租约过期",
+		})
+	if err != nil {
+		return failed, err
+	}
+	return failed + retried, nil
+}
+
+func scanJob(rows *sql.Rows) (*Job, error) {
+	job := new(Job)
+	var lockedUntil sql.NullTime
+	var lastError sql.NullString
+	err := rows.Scan(&job.Id, &job.Queue, &job.Payload, &job.Status, &job.Attempts, &job.MaxAttempts,
+		&job.RunAt, &job.LockedBy, &lockedUntil, &lastError)
+	if err != nil {
+		return nil, err
+	}
+	job.LockedUntil = lockedUntil.Time
+	job.LastError = lastError.String
+	return job, nil
+}
+
+//错误信息，过长时截断
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	text := err.Error()
+	if len(text) > 4096 {
+		text = strings.ToValidUTF8(text[:4096], "")
+	}
+	return text
+}
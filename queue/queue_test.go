@@ -0,0 +1,45 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(time.Second, 10*time.Second)
+	for attempt, max := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 10: 10 * time.Second} {
+		delay := backoff(attempt)
+		if delay < max/2 || delay > max {
+			t.Errorf("第 %d 次：%s 不在 [%s, %s] 范围内", attempt, delay, max/2, max)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	if q := New(nil, "emails"); q.err != nil {
+		t.Fatal(q.err)
+	}
+	for _, q := range []*Queue{
+		New(nil, ""),
+		New(nil, "emails", WithTable("jobs; DROP TABLE users")),
+		New(nil, "emails", WithLease(0)),
+		New(nil, "emails", WithMaxAttempts(0)),
+	} {
+		if _, err := q.Claim("worker", 1); err == nil {
+			t.Error("配置错误时应返回错误")
+		}
+	}
+	for _, limit := range []int{0, -1} {
+		if _, err := New(nil, "emails").Claim("worker", limit); err == nil {
+			t.Errorf("领取数量为 %d 时应返回错误", limit)
+		}
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	for _, name := range []string{"migrations/001_create_jobs.up.sql", "migrations/001_create_jobs.down.sql"} {
+		if _, err := Migrations.ReadFile(name); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+/**
+任务处理函数，返回错误时任务按退避时间重新执行
+ctx 在 Work 的 ctx 取消或租约失效时取消
+*/
+type Handler func(ctx context.Context, job *Job) error
+
+/**
+worker 配置
+*/
+type WorkerOptions struct {
+	//worker 标识，默认 主机名-进程号，每个并发协程使用 Name-序号
+	Name string
+	//并发协程数，默认 1
+	Concurrency int
+	//每次领取的任务数，默认 1
+	BatchSize int
+	//没有任务时的轮询间隔，默认 1 秒
+	PollInterval time.Duration
+	//回收过期任务的间隔，默认为租约时长
+	ReapInterval time.Duration
+	//领取、续期、回收等操作出错时的回调，默认忽略
+	OnError func(err error)
+}
+
+/**
+启动 worker 处理任务，阻塞直到 ctx 取消且正在执行的任务结束
+格式：q.Work(ctx, queue.WorkerOptions{Concurrency: 4}, func(ctx context.Context, job *queue.Job) error { ... })
+*/
+func (q *Queue) Work(ctx context.Context, opts WorkerOptions, handler Handler) error {
+	if q.err != nil {
+		return q.err
+	}
+	if handler == nil {
+		return fmt.Errorf("queue: 任务处理函数不能为空")
+	}
+	if opts.Name == "" {
+		host, _ := os.Hostname()
+		opts.Name = host + "-" + strconv.Itoa(os.Getpid())
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.ReapInterval <= 0 {
+		opts.ReapInterval = q.lease
+	}
+	if opts.OnError == nil {
+		opts.OnError = func(err error) {}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(opts.Concurrency + 1)
+	go func() {
+		defer wg.Done()
+		q.reapLoop(ctx, opts)
+	}()
+	for i := 0; i < opts.Concurrency; i++ {
+		worker := opts.Name + "-" + strconv.Itoa(i)
+		go func() {
+			defer wg.Done()
+			q.workLoop(ctx, worker, opts, handler)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+func (q *Queue) workLoop(ctx context.Context, worker string, opts WorkerOptions, handler Handler) {
+	for ctx.Err() == nil {
+		jobs, err := q.Claim(worker, opts.BatchSize)
+		if err != nil {
+			opts.OnError(err)
+		}
+		if len(jobs) == 0 {
+			sleep(ctx, opts.PollInterval)
+			continue
+		}
+		//领取后立即为整批任务续期，等待执行的任务租约不会过期
+		leases := make([]*lease, len(jobs))
+		for k, job := range jobs {
+			leases[k] = q.keepAlive(ctx, job, opts)
+		}
+		for k, job := range jobs {
+			//已取消时释放剩余的任务
+			if ctx.Err() != nil {
+				for _, l := range leases[k:] {
+					l.stop()
+				}
+				q.release(jobs[k:], opts)
+				return
+			}
+			q.process(ctx, job, leases[k], opts, handler)
+		}
+	}
+}
+
+//任务租约，从领取到执行结束定时续期，租约失效时取消 ctx
+type lease struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	exited chan struct{}
+}
+
+func (q *Queue) keepAlive(ctx context.Context, job *Job, opts WorkerOptions) *lease {
+	leaseCtx, cancel := context.WithCancel(ctx)
+	l := &lease{ctx: leaseCtx, cancel: cancel, done: make(chan struct{}), exited: make(chan struct{})}
+	go func() {
+		defer close(l.exited)
+		ticker := time.NewTicker(q.lease / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-l.done:
+				return
+			case <-ticker.C:
+				if err := q.Extend(job); err != nil {
+					opts.OnError(err)
+					if err == ErrLeaseLost {
+						cancel()
+						return
+					}
+				}
+			}
+		}
+	}()
+	return l
+}
+
+//停止续期，等待正在进行的续期结束
+func (l *lease) stop() {
+	close(l.done)
+	<-l.exited
+	l.cancel()
+}
+
+//执行单个任务，租约失效时不再执行
+func (q *Queue) process(ctx context.Context, job *Job, l *lease, opts WorkerOptions, handler Handler) {
+	if l.ctx.Err() != nil {
+		l.stop()
+		return
+	}
+	err := runHandler(l.ctx, job, handler)
+	l.stop()
+
+	//停止 worker 导致的失败不计入执行次数
+	if err != nil && ctx.Err() != nil {
+		q.release([]*Job{job}, opts)
+		return
+	}
+	if err == nil {
+		err = q.Done(job)
+	} else {
+		err = q.Fail(job, err)
+	}
+	if err != nil {
+		opts.OnError(err)
+	}
+}
+
+//执行任务处理函数，panic 转换为错误
+func runHandler(ctx context.Context, job *Job, handler Handler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("queue: 任务 %d panic: %v", job.Id, r)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+//释放未执行的任务，不计入执行次数
+func (q *Queue) release(jobs []*Job, opts WorkerOptions) {
+	for _, job := range jobs {
+		err := q.updateLocked(job, map[string]interface{}{
+			"status":       StatusPending,
+			"attempts":     job.Attempts - 1,
+			"locked_by":    "",
+			"locked_until": nil,
+		})
+		if err != nil {
+			opts.OnError(err)
+		}
+	}
+}
+
+func (q *Queue) reapLoop(ctx context.Context, opts WorkerOptions) {
+	for ctx.Err() == nil {
+		if _, err := q.Reap(); err != nil {
+			opts.OnError(err)
+		}
+		sleep(ctx, opts.ReapInterval)
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
@@ -0,0 +1,416 @@
+package corm
+
+import (
+	"database/sql"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+平均值，没有记录或值均为 NULL 时返回 ErrNotFound
+*/
+func (db *Db) Avg(field string) (float64, error) {
+	var avg sql.NullFloat64
+	err := db.aggregate("Avg", "AVG", field, &avg, func() bool { return avg.Valid })
+	if errs(err) != nil {
+		return 0, err
+	}
+	return avg.Float64, nil
+}
+
+/**
+精确求和，用于 DECIMAL 金额等字段，没有记录或值均为 NULL 时返回 ErrNotFound
+返回值可用 FloatString(2) 转换为字符串
+*/
+func (db *Db) SumDecimal(field string) (*big.Rat, error) {
+	var sum sql.NullString
+	err := db.aggregate("SumDecimal", "SUM", field, &sum, func() bool { return sum.Valid })
+	if errs(err) != nil {
+		return nil, err
+	}
+	rat := new(big.Rat)
+	if !sum.Valid {
+		return rat, nil
+	}
+	if _, ok := rat.SetString(sum.String); !ok {
+		return nil, methodErr("SumDecimal", "无法解析求和结果 %q", sum.String)
+	}
+	return rat, nil
+}
+
+/**
+时间最大值，连接串需设置 parseTime=true，没有记录或值均为 NULL 时返回 ErrNotFound
+*/
+func (db *Db) MaxTime(field string) (time.Time, error) {
+	return db.aggregateTime("MaxTime", "MAX", field)
+}
+
+/**
+时间最小值，连接串需设置 parseTime=true，没有记录或值均为 NULL 时返回 ErrNotFound
+*/
+func (db *Db) MinTime(field string) (time.Time, error) {
+	return db.aggregateTime("MinTime", "MIN", field)
+}
+
+/**
+浮点数最大值，没有记录或值均为 NULL 时返回 ErrNotFound
+*/
+func (db *Db) MaxFloat(field string) (float64, error) {
+	return db.aggregateFloat("MaxFloat", "MAX", field)
+}
+
+/**
+浮点数最小值，没有记录或值均为 NULL 时返回 ErrNotFound
+*/
+func (db *Db) MinFloat(field string) (float64, error) {
+	return db.aggregateFloat("MinFloat", "MIN", field)
+}
+
+/**
+字符串最大值，没有记录或值均为 NULL 时返回 ErrNotFound
+*/
+func (db *Db) MaxStr(field string) (string, error) {
+	return db.aggregateStr("MaxStr", "MAX", field)
+}
+
+/**
+字符串最小值，没有记录或值均为 NULL 时返回 ErrNotFound
+*/
+func (db *Db) MinStr(field string) (string, error) {
+	return db.aggregateStr("MinStr", "MIN", field)
+}
+
+func (db *Db) aggregateTime(method, fn, field string) (time.Time, error) {
+	var value sql.NullTime
+	err := db.aggregate(method, fn, field, &value, func() bool { return value.Valid })
+	if errs(err) != nil {
+		return time.Time{}, err
+	}
+	return value.Time, nil
+}
+
+func (db *Db) aggregateFloat(method, fn, field string) (float64, error) {
+	var value sql.NullFloat64
+	err := db.aggregate(method, fn, field, &value, func() bool { return value.Valid })
+	if errs(err) != nil {
+		return 0, err
+	}
+	return value.Float64, nil
+}
+
+func (db *Db) aggregateStr(method, fn, field string) (string, error) {
+	var value sql.NullString
+	err := db.aggregate(method, fn, field, &value, func() bool { return value.Valid })
+	if errs(err) != nil {
+		return "", err
+	}
+	return value.String, nil
+}
+
+/**
+查询单个聚合值，dest 为 sql.Null* 类型，valid 判断结果是否为 NULL
+结果为 NULL 时返回 ErrNotFound，兼容模式下与记录不存在一样被忽略
+*/
+func (db *Db) aggregate(method, fn, field string, dest interface{}, valid func() bool) error {
+	expr := fn + "(" + db.quoteColumn(method, field) + ")"
+	err := db.queryRow(db.aggregateToSql(expr), db.getWhereValue(), dest)
+	if err != nil {
+		return err
+	}
+	if !valid() {
+		return translateErr(sql.ErrNoRows)
+	}
+	return nil
+}
+
+func (db *Db) aggregateToSql(expr string) string {
+	db.check()
+	db.addWith()
+	db.addSelect()
+	db.writeBuf(expr, " AS agg", SPACE)
+	db.addFrom()
+	db.addTable()
+	db.addJoin()
+	db.addWhere()
+	db.addLock()
+	return db.buffer.String()
+}
+
+/**
+聚合函数，用于 Aggregate，格式：Count("*").As("n")、Sum("amount").As("total")
+*/
+type Agg struct {
+	fn       string
+	distinct bool
+	field    string
+	alias    string
+	err      []error
+}
+
+func newAgg(method, fn, field string, distinct bool) *Agg {
+	a := &Agg{fn: fn, distinct: distinct}
+	quoted, err := quoteName(field, !distinct)
+	if err == nil && quoted == "" {
+		err = fmt.Errorf("字段不能为空")
+	}
+	if err != nil {
+		a.err = append(a.err, methodErr(method, "%v", err))
+		quoted = field
+	}
+	a.field = quoted
+
+	//默认别名：count、sum_amount、count_distinct_user_id
+	alias := strings.ToLower(fn)
+	if distinct {
+		alias += "_distinct"
+	}
+	if field = field[strings.LastIndex(field, ".")+1:]; field != "*" {
+		alias += "_" + field
+	}
+	a.alias, _ = quoteIdent(alias)
+	return a
+}
+
+/**
+计数 COUNT(field)，field 可为 *
+*/
+func Count(field string) *Agg {
+	return newAgg("Count", "COUNT", field, false)
+}
+
+/**
+去重计数 COUNT(DISTINCT field)
+*/
+func CountDistinct(field string) *Agg {
+	return newAgg("CountDistinct", "COUNT", field, true)
+}
+
+/**
+求和 SUM(field)
+*/
+func Sum(field string) *Agg {
+	return newAgg("Sum", "SUM", field, false)
+}
+
+/**
+平均值 AVG(field)
+*/
+func Avg(field string) *Agg {
+	return newAgg("Avg", "AVG", field, false)
+}
+
+/**
+最大值 MAX(field)
+*/
+func Max(field string) *Agg {
+	return newAgg("Max", "MAX", field, false)
+}
+
+/**
+最小值 MIN(field)
+*/
+func Min(field string) *Agg {
+	return newAgg("Min", "MIN", field, false)
+}
+
+/**
+别名，用于从 AggRow 中取值，默认为 count、sum_amount 等
+*/
+func (a *Agg) As(alias string) *Agg {
+	quoted, ok := quoteIdent(alias)
+	if !ok {
+		a.err = append(a.err, methodErr("As", "非法别名 %q", alias))
+		return a
+	}
+	a.alias = quoted
+	return a
+}
+
+func (a *Agg) toSql() string {
+	field := a.field
+	if a.distinct {
+		field = DISTINCT + SPACE + field
+	}
+	return a.fn + "(" + field + ") " + AS + SPACE + a.alias
+}
+
+/**
+聚合结果的一行，Group 为分组字段的值，聚合值通过别名获取
+*/
+type AggRow struct {
+	Group  map[string]interface{}
+	values map[string]interface{}
+	keys   []interface{}
+}
+
+/**
+聚合结果，按 OrderBy 排序
+*/
+type AggRows []*AggRow
+
+/**
+按分组字段的值查找，顺序与 GroupBy 一致，格式：Find(groupId)、Find(year, month)
+*/
+func (rows AggRows) Find(group ...interface{}) *AggRow {
+	for _, row := range rows {
+		if len(row.keys) != len(group) {
+			continue
+		}
+		match := true
+		for k, v := range group {
+			if fmt.Sprint(row.keys[k]) != fmt.Sprint(v) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return row
+		}
+	}
+	return nil
+}
+
+/**
+聚合值，NULL 时返回 nil
+*/
+func (row *AggRow) Value(alias string) interface{} {
+	return row.values[alias]
+}
+
+/**
+聚合值是否为 NULL（没有记录时 SUM、MAX 等为 NULL）
+*/
+func (row *AggRow) IsNull(alias string) bool {
+	return row.values[alias] == nil
+}
+
+/**
+以 int64 返回聚合值，NULL 或无法转换时返回 0
+*/
+func (row *AggRow) Int64(alias string) int64 {
+	switch v := row.values[alias].(type) {
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	case string:
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			f, _ := strconv.ParseFloat(v, 64)
+			return int64(f)
+		}
+		return i
+	}
+	return 0
+}
+
+/**
+以 float64 返回聚合值，NULL 或无法转换时返回 0
+*/
+func (row *AggRow) Float64(alias string) float64 {
+	switch v := row.values[alias].(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	}
+	return 0
+}
+
+/**
+以字符串返回聚合值，NULL 时返回空字符串
+*/
+func (row *AggRow) String(alias string) string {
+	v := row.values[alias]
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+/**
+以 *big.Rat 返回精确的聚合值，用于 DECIMAL 字段，NULL 或无法转换时返回 nil
+*/
+func (row *AggRow) Decimal(alias string) *big.Rat {
+	v := row.values[alias]
+	if v == nil {
+		return nil
+	}
+	rat, ok := new(big.Rat).SetString(fmt.Sprint(v))
+	if !ok {
+		return nil
+	}
+	return rat
+}
+
+/**
+一次查询多个聚合值，支持 GroupBy、Having、OrderBy、Limit，格式：
+Tab("orders").GroupBy("user_id").Aggregate(Count("*").As("n"), Sum("amount").As("total"), Max("created_at"))
+查询字段为 GroupBy 的字段及聚合函数，之前的 Select 会被忽略
+*/
+func (db *Db) Aggregate(aggs ...*Agg) (AggRows, error) {
+	if len(aggs) == 0 {
+		db.pushErr(methodErr("Aggregate", "聚合函数不能为空"))
+	}
+	db.fields = nil
+	for _, g := range db.groupBy {
+		db.fields = append(db.fields, fieldExpr(g, nil))
+	}
+	for _, a := range aggs {
+		if a == nil {
+			db.pushErr(methodErr("Aggregate", "聚合函数不能为空"))
+			continue
+		}
+		for _, err := range a.err {
+			db.pushErr(methodErr("Aggregate", "%w", err))
+		}
+		db.fields = append(db.fields, fieldExpr(a.toSql(), nil))
+	}
+	groups := len(db.groupBy)
+
+	rows, err := db.query(db.whereToSql(), db.getWhereValue()...)
+	if errs(err) != nil {
+		return nil, err
+	}
+	if rows == nil {
+		return nil, nil
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var result AggRows
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		dest := make([]interface{}, len(columns))
+		for k := range values {
+			dest[k] = &values[k]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		row := &AggRow{Group: make(map[string]interface{}, groups), values: make(map[string]interface{}, len(columns)-groups)}
+		for k, column := range columns {
+			value := values[k]
+			if b, ok := value.([]byte); ok {
+				value = string(b)
+			}
+			if k < groups {
+				row.Group[column] = value
+				row.keys = append(row.keys, value)
+			} else {
+				row.values[column] = value
+			}
+		}
+		result = append(result, row)
+	}
+	return result, translateErr(rows.Err())
+}
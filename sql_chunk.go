@@ -0,0 +1,145 @@
+package corm
+
+import (
+	"database/sql"
+	"math"
+	"strings"
+)
+
+/**
+按主键 id 分块遍历，等同于 ChunkByID("id", size, callable)
+*/
+func (db *Db) Chunk(size int, callable func(rows *sql.Rows) error) error {
+	return db.ChunkByID("id", size, callable)
+}
+
+/**
+按整数主键分块遍历大表，用于数据回填、导出等，格式：
+Tab("users").Select("id", "name").Where("status", "=", 1).ChunkByID("id", 1000, func(rows *sql.Rows) error {
+	for rows.Next() {
+		...
+	}
+	return nil
+})
+每块先查询本块主键的上界，再按 (上一块上界, 本块上界] 查询数据，不使用 OFFSET，遍历期间插入、删除数据不会导致重复或遗漏
+idColumn 主键字段，必须为整数
+size 每块记录数
+callable 每块调用一次，返回错误时停止遍历并返回该错误
+*/
+func (db *Db) ChunkByID(idColumn string, size int, callable func(rows *sql.Rows) error) error {
+	return db.chunk("ChunkByID", idColumn, size, func(chunk *Db) error {
+		return chunk.chunkRows(callable)
+	})
+}
+
+/**
+按主键 id 分块遍历，每块在单独的事务中执行，等同于 ChunkByIDTx("id", size, callable)
+*/
+func (db *Db) ChunkTx(size int, callable func(dbTrans *Db, rows *sql.Rows) error) error {
+	return db.ChunkByIDTx("id", size, callable)
+}
+
+/**
+按整数主键分块遍历，每块在单独的事务中查询并调用 callable，callable 返回错误时回滚当前块并停止遍历，之前的块已提交
+rows 与 dbTrans 使用同一连接，需读取完 rows 或调用 rows.Close() 后再使用 dbTrans 执行其他语句，格式：
+Tab("users").Select("id").Where("score", "=", 0).ChunkByIDTx("id", 500, func(dbTrans *Db, rows *sql.Rows) error {
+	var ids []interface{}
+	for rows.Next() {
+		...
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	_, err := dbTrans.Tab("users").WhereIn("id", ids...).Update(map[string]interface{}{"score": 100})
+	return err
+})
+*/
+func (db *Db) ChunkByIDTx(idColumn string, size int, callable func(dbTrans *Db, rows *sql.Rows) error) error {
+	return db.chunk("ChunkByIDTx", idColumn, size, func(chunk *Db) error {
+		return db.Tab("").Transaction(func(dbTrans *Db) error {
+			chunk.tx, chunk.scope = dbTrans.tx, dbTrans.scope
+			return chunk.chunkRows(func(rows *sql.Rows) error {
+				return callable(dbTrans, rows)
+			})
+		})
+	})
+}
+
+//按主键范围分块，每块生成一个带范围条件的查询交给 fn 执行，db 作为模板不会被修改
+func (db *Db) chunk(method, idColumn string, size int, fn func(chunk *Db) error) error {
+	defer db.putPool()
+
+	id := db.quoteColumn(method, idColumn)
+	if size < 1 {
+		db.pushErr(methodErr(method, "每块记录数必须大于 0：%d", size))
+	}
+	if len(db.unions) > 0 || len(db.groupBy) > 0 || len(db.having) > 0 {
+		db.pushErr(methodErr(method, "不能用于 Union、GroupBy、Having 查询"))
+	}
+	if db.limit > 0 || db.offset > 0 {
+		db.pushErr(methodErr(method, "不能与 Limit、Offset 一起使用"))
+	}
+	if err := db.getErr(); err != nil {
+		return err
+	}
+
+	var from int64 = math.MinInt64
+	for {
+		to, ok, err := db.chunkBound(id, from, size)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+
+		chunk := db.chunkRange(id, from, to)
+		if len(chunk.orderBy) == 0 {
+			chunk.orderBy = []orderBy{{method: method, field: id, by: "ASC"}}
+		}
+		if err := fn(chunk); err != nil {
+			return err
+		}
+		from = to
+	}
+}
+
+//查询 from 之后 size 条记录的最大主键，没有记录时 ok 为 false
+func (db *Db) chunkBound(id string, from int64, size int) (to int64, ok bool, err error) {
+	sub := db.chunkRange(id, from, math.MaxInt64)
+	sub.fields = []field{fieldExpr(id, nil)}
+	sub.orderBy = []orderBy{{method: "Chunk", field: id, by: "ASC"}}
+	sub.limit = size
+
+	var bound sql.NullInt64
+	name := id[strings.LastIndex(id, ".")+1:]
+	err = db.TabSub(sub, "c").SelectRaw("MAX(" + name + ")").First(&bound)
+	if errs(err) != nil {
+		return 0, false, err
+	}
+	return bound.Int64, bound.Valid, nil
+}
+
+//克隆查询并追加主键范围 (from, to] 条件
+func (db *Db) chunkRange(id string, from, to int64) *Db {
+	chunk := db.clone()
+	chunk.where = db.groupedWhere("Chunk")
+	return chunk.pushWhereRaw("Chunk", id+" > ? AND "+id+" <= ?", []interface{}{from, to})
+}
+
+//查询当前块，rows 在 callable 返回后关闭
+func (db *Db) chunkRows(callable func(rows *sql.Rows) error) error {
+	rows, err := db.query(db.whereToSql(), db.getWhereValue()...)
+	if errs(err) != nil {
+		return err
+	}
+	if rows == nil {
+		return nil
+	}
+	defer rows.Close()
+
+	if err := callable(rows); err != nil {
+		return err
+	}
+	return translateErr(rows.Err())
+}
@@ -32,6 +32,9 @@ func (db *Db) addInsert() {
 }
 
 func (db *Db) addTable() {
+	if db.tabSub != nil {
+		db.writeBuf("(", db.subSql(db.tabSub), ") ", AS, SPACE)
+	}
 	db.writeBuf(db.table, SPACE)
 	db.writeBuf(db.force, SPACE)
 }
@@ -66,9 +69,19 @@ func (db *Db) addMin() {
 }
 
 func (db *Db) addCount() {
+	if db.count != "" {
+		db.writeBuf(db.count, " AS count", SPACE)
+		return
+	}
 	db.writeBuf("COUNT(*) AS count", SPACE)
 }
 
+func (db *Db) addDistinct() {
+	if db.distinct {
+		db.writeBuf(DISTINCT, SPACE)
+	}
+}
+
 /**
 添加字段
 */
@@ -79,13 +92,27 @@ func (db *Db) addFields() {
 	} else {
 		var fieldStr []string
 		for _, f := range db.fields {
-			fieldStr = append(fieldStr, f)
+			if f.sub != nil {
+				fieldStr = append(fieldStr, "("+db.subSql(f.sub)+") "+AS+SPACE+f.expr)
+				continue
+			}
+			fieldStr = append(fieldStr, f.expr)
+			db.args = append(db.args, f.args...)
 		}
 		sqlStr = strings.Join(fieldStr, COMMA)
 	}
 	db.writeBuf(sqlStr, SPACE)
 }
 
+/**
+生成子查询语句，子查询的参数按位置合并到当前语句
+*/
+func (db *Db) subSql(sub *Db) string {
+	sqlStr := strings.TrimSpace(sub.whereToSql())
+	db.args = append(db.args, sub.getWhereValue()...)
+	return sqlStr
+}
+
 /**
 添加join
 */
@@ -93,7 +120,11 @@ func (db *Db) addJoin() {
 	if len(db.join) > 0 {
 		join := make([]string, 0, 2)
 		for _, j := range db.join {
-			join = append(join, strings.Join([]string{j.direction, j.table, ON, j.on}, SPACE))
+			on := j.on
+			if len(j.onWhere) > 0 {
+				on = db.whereSql(j.onWhere)
+			}
+			join = append(join, strings.Join([]string{j.direction, j.table, ON, on}, SPACE))
 		}
 		db.writeBuf(strings.Join(join, SPACE), SPACE)
 	}
@@ -103,29 +134,60 @@ func (db *Db) addJoin() {
 添加where条件
 */
 func (db *Db) addWhere() {
-	if len(db.whereRaw) > 0 || len(db.where) > 0 {
-		sqlTmp := make([]string, 0, 5)
-		if len(db.where) > 0 {
-			for _, w := range db.where {
-				switch w.operator {
-				case IN, NOT_IN:
-					sqlTmp = append(sqlTmp, w.field+SPACE+w.operator+"("+arrayToStrPlace(w.conditionArray)+")")
-				case LIKE, NOT_LIKE:
-					sqlTmp = append(sqlTmp, w.field+SPACE+w.operator+SPACE+QUES)
-				case BETWEEN:
-					sqlTmp = append(sqlTmp, w.field+SPACE+w.operator+SPACE+QUES+SPACE+AND+SPACE+QUES)
-				default:
-					sqlTmp = append(sqlTmp, w.field+SPACE+w.operator+SPACE+QUES)
-				}
+	if len(db.where) > 0 {
+		db.writeBuf(WHERE, SPACE, db.whereSql(db.where), SPACE)
+	}
+}
+
+/**
+生成条件语句，并按顺序收集参数
+*/
+func (db *Db) whereSql(list []where) string {
+	var buf strings.Builder
+	for i, w := range list {
+		if i > 0 {
+			if w.or {
+				buf.WriteString(SPACE + OR + SPACE)
+			} else {
+				buf.WriteString(SPACE + AND + SPACE)
 			}
 		}
-		if len(db.whereRaw) > 0 {
-			for _, w := range db.whereRaw {
-				sqlTmp = append(sqlTmp, w)
+		switch {
+		case w.group != nil:
+			buf.WriteString("(" + db.whereSql(w.group) + ")")
+			continue
+		case w.raw != "":
+			buf.WriteString("(" + w.raw + ")")
+			db.args = append(db.args, w.conditionArray...)
+			continue
+		case w.column != "":
+			buf.WriteString(w.field + SPACE + w.operator + SPACE + w.column)
+			continue
+		case w.sub != nil:
+			if w.field != "" {
+				buf.WriteString(w.field + SPACE)
 			}
+			buf.WriteString(w.operator + " (" + db.subSql(w.sub) + ")")
+			continue
+		}
+		switch w.operator {
+		case IN, NOT_IN:
+			buf.WriteString(w.field + SPACE + w.operator + "(" + arrayToStrPlace(w.conditionArray) + ")")
+			db.args = append(db.args, w.conditionArray...)
+		case LIKE, NOT_LIKE:
+			buf.WriteString(w.field + SPACE + w.operator + SPACE + QUES)
+			db.args = append(db.args, w.condition)
+		case BETWEEN:
+			buf.WriteString(w.field + SPACE + w.operator + SPACE + QUES + SPACE + AND + SPACE + QUES)
+			db.args = append(db.args, w.conditionArray...)
+		case IS_NULL, NOT_NULL:
+			buf.WriteString(w.field + SPACE + w.operator)
+		default:
+			buf.WriteString(w.field + SPACE + w.operator + SPACE + QUES)
+			db.args = append(db.args, w.condition)
 		}
-		db.writeBuf(WHERE, SPACE, strings.Join(sqlTmp, " AND "), SPACE)
 	}
+	return buf.String()
 }
 
 /**
@@ -154,6 +216,15 @@ func (db *Db) addGroupBy() {
 	}
 }
 
+/**
+添加分组过滤条件
+*/
+func (db *Db) addHaving() {
+	if len(db.having) > 0 {
+		db.writeBuf(HAVING, SPACE, db.whereSql(db.having), SPACE)
+	}
+}
+
 /**
 添加limit
 */
@@ -171,20 +242,126 @@ func (db *Db) addLimit() {
 */
 func (db *Db) whereToSql() string {
 	db.check()
+	db.addWith()
+	if len(db.unions) > 0 {
+		db.writeBuf("(")
+	}
 	db.addSelect()
+	db.addDistinct()
 	db.addFields()
 	db.addFrom()
 	db.addTable()
 	db.addJoin()
 	db.addWhere()
 	db.addGroupBy()
+	db.addHaving()
+	db.addWindow()
+	db.addUnion()
+	db.addOrderBy()
+	db.addLimit()
+	db.addLock()
+	return db.buffer.String()
+}
+
+/**
+添加行锁，方言不支持时在 validate 中返回错误
+*/
+func (db *Db) addLock() {
+	if db.lock == "" {
+		return
+	}
+	if clause, err := dialect.Lock(db.lock, db.wait); err == nil {
+		db.writeBuf(SPACE, clause)
+	}
+}
+
+/**
+添加 WITH 公用表表达式，有递归 CTE 时使用 WITH RECURSIVE
+*/
+func (db *Db) addWith() {
+	if len(db.ctes) == 0 {
+		return
+	}
+	db.writeBuf(WITH, SPACE)
+	for _, c := range db.ctes {
+		if c.recursive != nil {
+			db.writeBuf(RECURSIVE, SPACE)
+			break
+		}
+	}
+	for i, c := range db.ctes {
+		if i > 0 {
+			db.writeBuf(COMMA, SPACE)
+		}
+		db.writeBuf(c.name)
+		if len(c.columns) > 0 {
+			db.writeBuf("(", strings.Join(c.columns, COMMA), ")")
+		}
+		db.writeBuf(SPACE, AS, " (", db.subSql(c.sub))
+		if c.recursive != nil {
+			db.writeBuf(SPACE, UNION_ALL, SPACE, db.subSql(c.recursive))
+		}
+		db.writeBuf(")")
+	}
+	db.writeBuf(SPACE)
+}
+
+/**
+添加 UNION，基础查询及每个联合查询用括号包裹，Union 前的 ORDER BY、LIMIT 在基础查询的括号内
+*/
+func (db *Db) addUnion() {
+	if len(db.unions) == 0 {
+		return
+	}
+	order, limit, offset := db.orderBy, db.limit, db.offset
+	db.orderBy, db.limit, db.offset = db.base.orderBy, db.base.limit, db.base.offset
 	db.addOrderBy()
 	db.addLimit()
+	db.orderBy, db.limit, db.offset = order, limit, offset
+
+	sqlStr := strings.TrimSpace(db.buffer.String())
+	db.buffer.Reset()
+	db.writeBuf(sqlStr, ")")
+	for _, u := range db.unions {
+		if u.all {
+			db.writeBuf(SPACE, UNION_ALL, SPACE)
+		} else {
+			db.writeBuf(SPACE, UNION, SPACE)
+		}
+		db.writeBuf("(", db.subSql(u.db), ")")
+	}
+	db.writeBuf(SPACE)
+}
+
+/**
+将查询作为派生表统计总数，用于 UNION 等无法直接 COUNT 的查询
+*/
+func (db *Db) wrapCountToSql() string {
+	limit, offset, order, fields := db.limit, db.offset, db.orderBy, db.fields
+	db.limit, db.offset, db.orderBy = 0, 0, nil
+	//未指定字段时只查询分组字段，避免 SELECT * 在 ONLY_FULL_GROUP_BY 下报错及关联查询字段重名
+	if len(db.fields) == 0 && len(db.groupBy) > 0 && !db.distinct && len(db.unions) == 0 {
+		db.fields = []field{fieldExpr(strings.Join(db.groupBy, ","), nil)}
+	}
+	inner := strings.TrimSpace(db.whereToSql())
+	db.limit, db.offset, db.orderBy, db.fields = limit, offset, order, fields
+
+	args := db.args
+	db.check()
+	db.args = args
+	db.addSelect()
+	db.addCount()
+	db.writeBuf(FROM, " (", inner, ") ", AS, " t")
 	return db.buffer.String()
 }
 
 func (db *Db) countToSql() string {
+	//联合、分组、去重查询统计子查询的结果行数
+	if len(db.unions) > 0 || len(db.groupBy) > 0 || len(db.having) > 0 || db.distinct {
+		return db.wrapCountToSql()
+	}
 	db.check()
+	db.addWith()
 	db.addSelect()
 	db.addCount()
 	db.addFrom()
@@ -192,22 +369,27 @@ func (db *Db) countToSql() string {
 	db.addJoin()
 	db.addWhere()
 	db.addLimit()
+	//聚合查询同样加锁，锁定统计范围内的行
+	db.addLock()
 	return db.buffer.String()
 }
 
 func (db *Db) sumToSql() string {
 	db.check()
+	db.addWith()
 	db.addSelect()
 	db.addSum()
 	db.addFrom()
 	db.addTable()
 	db.addJoin()
 	db.addWhere()
+	db.addLock()
 	return db.buffer.String()
 }
 
 func (db *Db) maxToSql() string {
 	db.check()
+	db.addWith()
 	db.addSelect()
 	db.addMax()
 	db.addFrom()
@@ -216,11 +398,13 @@ func (db *Db) maxToSql() string {
 	db.addWhere()
 	db.addOrderBy()
 	db.addLimit()
+	db.addLock()
 	return db.buffer.String()
 }
 
 func (db *Db) minToSql() string {
 	db.check()
+	db.addWith()
 	db.addSelect()
 	db.addMin()
 	db.addFrom()
@@ -229,6 +413,7 @@ func (db *Db) minToSql() string {
 	db.addWhere()
 	db.addOrderBy()
 	db.addLimit()
+	db.addLock()
 	return db.buffer.String()
 }
 
@@ -238,12 +423,12 @@ func (db *Db) insertToStrAndArr() (string, []interface{}) {
 	var vals []interface{}
 
 	for k, v := range db.insert {
-		keys = append(keys, k)
+		keys = append(keys, db.quoteKey("Insert", k))
 		keyVals = append(keyVals, "?")
 		vals = append(vals, v)
 	}
 
-	keysToStr := db.table + "(`" + strings.Join(keys, "`, `") + "`)"
+	keysToStr := db.table + "(" + strings.Join(keys, ", ") + ")"
 	keyValsToStr := " VALUES(" + strings.Join(keyVals, ", ") + ")"
 	insertStr := keysToStr + keyValsToStr
 
@@ -255,13 +440,23 @@ func (db *Db) updateToStrAndArr() (string, []interface{}) {
 	var vals []interface{}
 
 	for k, v := range db.update {
-		keys = append(keys, "`"+k+"` = ?")
+		keys = append(keys, db.quoteKey("Update", k)+" = ?")
 		vals = append(vals, v)
 	}
 
 	return strings.Join(keys, COMMA), vals
 }
 
+//引用插入、更新的字段名
+func (db *Db) quoteKey(method, key string) string {
+	quoted, ok := quoteIdent(key)
+	if !ok {
+		db.pushErr(methodErr(method, "非法字段名 %q", key))
+		return key
+	}
+	return quoted
+}
+
 func (db *Db) insertToSql() (sql string, arr []interface{}) {
 	db.check()
 	insertStr, vals := db.insertToStrAndArr()
@@ -274,14 +469,28 @@ func (db *Db) insertToSql() (sql string, arr []interface{}) {
 
 func (db *Db) updateToSql() (sql string, arr []interface{}) {
 	db.check()
+	db.addWith()
 	updateStr, vals := db.updateToStrAndArr()
+	db.args = append(db.args, vals...)
 
 	db.addUpdate()
 	db.addTable()
 	db.addSet()
 	db.writeBuf(updateStr, SPACE)
 	db.addWhere()
-	return retSql(db.buffer.String()), vals
+	return retSql(db.buffer.String()), db.getWhereValue()
+}
+
+func (db *Db) deleteToSql() string {
+	db.check()
+	db.addWith()
+	db.addDelete()
+	db.addFrom()
+	db.addTable()
+	db.addWhere()
+	db.addOrderBy()
+	db.addLimit()
+	return db.buffer.String()
 }
 
 func retSql(sqlStr string) string {
@@ -305,21 +514,9 @@ func arrayToStrPlace(arr []interface{}) string {
 	return strings.Join(strTmp, ",")
 }
 
+/**
+生成SQL时按顺序收集的参数，需在生成SQL之后调用
+*/
 func (db *Db) getWhereValue() []interface{} {
-	where := make([]interface{}, 0, 5)
-	if len(db.where) > 0 {
-		for _, w := range db.where {
-			switch w.operator {
-			case IN, NOT_IN:
-				where = append(where, w.conditionArray...)
-			case LIKE, NOT_LIKE:
-				where = append(where, w.condition)
-			case BETWEEN:
-				where = append(where, w.conditionArray...)
-			default:
-				where = append(where, w.condition)
-			}
-		}
-	}
-	return where
+	return db.args
 }
@@ -3,6 +3,7 @@ package corm
 const (
 	INSERT     = "INSERT INTO"
 	SELECT     = "SELECT"
+	DISTINCT   = "DISTINCT"
 	UPDATE     = "UPDATE"
 	DELETE     = "DELETE"
 	FROM       = "FROM"
@@ -11,6 +12,7 @@ const (
 	LEFT_JOIN  = "LEFT JOIN"
 	RIGHT_JOIN = "RIGHT JOIN"
 	AND        = "AND"
+	OR         = "OR"
 	ON         = "ON"
 	IN         = "IN"
 	NOT_IN     = "NOT IN"
@@ -22,6 +24,21 @@ const (
 	OFFSET     = "OFFSET"
 	HAVING     = "HAVING"
 	BETWEEN    = "BETWEEN"
+	IS_NULL    = "IS NULL"
+	NOT_NULL   = "IS NOT NULL"
+	NULL_SAFE  = "<=>"
+	EXISTS     = "EXISTS"
+	NOT_EXISTS = "NOT EXISTS"
+	AS         = "AS"
+	UNION      = "UNION"
+	UNION_ALL  = "UNION ALL"
+	WITH       = "WITH"
+	RECURSIVE  = "RECURSIVE"
+	WINDOW     = "WINDOW"
+	FOR_UPDATE = "FOR UPDATE"
+	FOR_SHARE  = "FOR SHARE"
+	SKIP_LOCK  = "SKIP LOCKED"
+	NOWAIT     = "NOWAIT"
 	SET        = "SET"
 	SPACE      = " "
 	COMMA      = ","
@@ -0,0 +1,178 @@
+package corm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+/**
+数据库方言，负责生成与数据库相关的SQL片段
+构造器及 Raw、WhereRaw 等原生SQL统一使用 ? 作为占位符，执行前按顺序替换为 Placeholder(1)、Placeholder(2)…
+*/
+type Dialect interface {
+	//引用单个标识符（表名、字段名、别名）
+	QuoteIdent(name string) string
+	//第 n 个参数的占位符，n 从 1 开始，如 MySQL 的 ?、PostgreSQL 的 $n
+	Placeholder(n int) string
+	//拼接字符串
+	Concat(list []string) string
+	//值为 NULL 时返回默认值
+	IfNull(expr, def string) string
+	//行锁子句，lock 为 FOR UPDATE 或 FOR SHARE，wait 为 SKIP LOCKED、NOWAIT 或空
+	Lock(lock, wait string) (string, error)
+}
+
+/**
+MySQL 方言，Version 为服务器版本，如 "5.7"，为空时按 8.0 处理
+5.7 的共享锁使用 LOCK IN SHARE MODE，且不支持 SKIP LOCKED、NOWAIT
+*/
+type MySQL struct {
+	Version string
+}
+
+func (MySQL) QuoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func (MySQL) Placeholder(int) string {
+	return QUES
+}
+
+func (MySQL) Concat(list []string) string {
+	return "CONCAT(" + strings.Join(list, ", ") + ")"
+}
+
+func (MySQL) IfNull(expr, def string) string {
+	return "IFNULL(" + expr + ", " + def + ")"
+}
+
+func (m MySQL) Lock(lock, wait string) (string, error) {
+	if strings.HasPrefix(m.Version, "5.") {
+		if wait != "" {
+			return "", fmt.Errorf("MySQL %s 不支持 %s", m.Version, wait)
+		}
+		if lock == FOR_SHARE {
+			return "LOCK IN SHARE MODE", nil
+		}
+		return lock, nil
+	}
+	if wait != "" {
+		return lock + SPACE + wait, nil
+	}
+	return lock, nil
+}
+
+var dialect Dialect = MySQL{}
+
+/**
+设置数据库方言，默认为 MySQL，需在程序启动时设置，MySQL 5.7 请使用 SetDialect(MySQL{Version: "5.7"})
+*/
+func SetDialect(d Dialect) {
+	dialect = d
+}
+
+//按顺序将SQL中引号外的 ? 替换为方言的占位符，返回替换后的SQL及占位符数量
+func bindPlaceholders(sqlStr string) (string, int) {
+	var buf strings.Builder
+	count := 0
+	var quote rune
+	escaped := false
+	for _, c := range sqlStr {
+		switch {
+		case escaped:
+			escaped = false
+		case quote != 0:
+			if c == '\\' && quote != '`' {
+				escaped = true
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '?':
+			count++
+			buf.WriteString(dialect.Placeholder(count))
+			continue
+		}
+		buf.WriteRune(c)
+	}
+	return buf.String(), count
+}
+
+//合法的标识符：字母、数字、下划线、$，不能以数字开头；或已用反引号引用
+var identRegexp = regexp.MustCompile("^(?:[\\p{L}_][\\p{L}\\p{N}_$]*|`[^`]+`)$")
+
+func quoteIdent(name string) (string, bool) {
+	if !identRegexp.MatchString(name) {
+		return "", false
+	}
+	return dialect.QuoteIdent(strings.Trim(name, "`")), true
+}
+
+/**
+引用名称，支持 table.column、db.table、column alias、column AS alias
+star 是否允许 * 及 table.*
+*/
+func quoteName(expr string, star bool) (string, error) {
+	if expr == "" {
+		return "", nil
+	}
+	parts := strings.Fields(expr)
+	var name, alias string
+	switch {
+	case len(parts) == 1:
+		name = parts[0]
+	case len(parts) == 2:
+		name, alias = parts[0], parts[1]
+	case len(parts) == 3 && strings.EqualFold(parts[1], "AS"):
+		name, alias = parts[0], parts[2]
+	default:
+		return "", fmt.Errorf("非法名称 %q", expr)
+	}
+
+	segs := strings.Split(name, ".")
+	if len(segs) > 3 {
+		return "", fmt.Errorf("非法名称 %q", expr)
+	}
+	for i, seg := range segs {
+		if star && seg == "*" && i == len(segs)-1 && alias == "" {
+			continue
+		}
+		quoted, ok := quoteIdent(seg)
+		if !ok {
+			return "", fmt.Errorf("非法名称 %q", expr)
+		}
+		segs[i] = quoted
+	}
+	quoted := strings.Join(segs, ".")
+
+	if alias != "" {
+		quotedAlias, ok := quoteIdent(alias)
+		if !ok {
+			return "", fmt.Errorf("非法别名 %q", expr)
+		}
+		quoted += " AS " + quotedAlias
+	}
+	return quoted, nil
+}
+
+//引用字段名，出错时记录错误并返回原值
+func (db *Db) quoteColumn(method, field string) string {
+	quoted, err := quoteName(field, true)
+	if err != nil {
+		db.pushErr(methodErr(method, "%v", err))
+		return field
+	}
+	return quoted
+}
+
+//引用表名，出错时记录错误并返回原值
+func (db *Db) quoteTable(method, table string) string {
+	quoted, err := quoteName(table, false)
+	if err != nil {
+		db.pushErr(methodErr(method, "%v", err))
+		return table
+	}
+	return quoted
+}
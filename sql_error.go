@@ -0,0 +1,84 @@
+package corm
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+/**
+数据库错误类型，返回的错误包装了原始错误，可用 errors.Is 判断类型，errors.As 获取 *mysql.MySQLError
+*/
+var (
+	ErrNotFound     = errors.New("记录不存在")
+	ErrDuplicateKey = errors.New("唯一键冲突")
+	ErrForeignKey   = errors.New("外键约束失败")
+	ErrDeadlock     = errors.New("死锁")
+	ErrLockTimeout  = errors.New("锁等待超时")
+	ErrDataTooLong  = errors.New("数据超出字段长度")
+)
+
+//MySQL 错误码对应的错误类型
+var mysqlErrors = map[uint16]error{
+	1062: ErrDuplicateKey,
+	1216: ErrForeignKey,
+	1217: ErrForeignKey,
+	1451: ErrForeignKey,
+	1452: ErrForeignKey,
+	1213: ErrDeadlock,
+	1205: ErrLockTimeout,
+	1406: ErrDataTooLong,
+}
+
+var compatErrors atomic.Bool
+
+/**
+兼容模式：开启后与旧版本一致，忽略记录不存在及字段值为 NULL 时的扫描错误，返回类型零值
+*/
+func SetCompatErrors(on bool) {
+	compatErrors.Store(on)
+}
+
+/**
+将数据库返回的错误转换为对应的错误类型
+*/
+func translateErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		kind, ok := mysqlErrors[mysqlErr.Number]
+		if ok && !errors.Is(err, kind) {
+			return fmt.Errorf("%w: %w", kind, err)
+		}
+	}
+	return err
+}
+
+/**
+兼容模式下忽略的错误返回 nil
+*/
+func errs(err error) error {
+	if err != nil && compatErrors.Load() {
+		//数据库记录不存在，报此错，可忽略
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		//字段值为NULL时，报此错，可忽略，默认为类型的零值
+		if strings.Index(err.Error(), "sql: Scan error on column index") != -1 {
+			return nil
+		}
+	}
+	return err
+}
@@ -0,0 +1,214 @@
+package corm
+
+import (
+	"errors"
+	"strings"
+)
+
+/**
+SQL 表达式，由 Col、Val、Coalesce、IfNull、Concat、Case 创建，通过 SelectExpr 作为查询字段
+表达式中的值均使用占位符绑定，按出现顺序排在 where 参数之前
+*/
+type Expr interface {
+	build() (string, []interface{}, error)
+}
+
+type expr struct {
+	sql  string
+	args []interface{}
+	err  error
+}
+
+func (e expr) build() (string, []interface{}, error) {
+	return e.sql, e.args, e.err
+}
+
+/**
+字段，格式：Col("u.name")
+*/
+func Col(field string) Expr {
+	quoted, err := quoteName(field, false)
+	if err == nil && quoted == "" {
+		err = errors.New("字段不能为空")
+	}
+	if err != nil {
+		return expr{err: methodErr("Col", "%v", err)}
+	}
+	return expr{sql: quoted}
+}
+
+/**
+值，使用占位符绑定；表达式函数中非 Expr 类型的参数会自动转换为 Val
+*/
+func Val(value interface{}) Expr {
+	return expr{sql: QUES, args: []interface{}{value}}
+}
+
+func toExpr(value interface{}) Expr {
+	if e, ok := value.(Expr); ok && e != nil {
+		return e
+	}
+	return Val(value)
+}
+
+//生成多个表达式，返回各自的SQL及按顺序合并的参数
+func buildExprs(list []interface{}) ([]string, []interface{}, error) {
+	sqls := make([]string, 0, len(list))
+	var args []interface{}
+	var errs []error
+	for _, v := range list {
+		sqlStr, vals, err := toExpr(v).build()
+		if err != nil {
+			errs = append(errs, err)
+		}
+		sqls = append(sqls, sqlStr)
+		args = append(args, vals...)
+	}
+	return sqls, args, errors.Join(errs...)
+}
+
+/**
+返回第一个非 NULL 的值，格式：Coalesce(Col("nickname"), Col("name"), "匿名")
+*/
+func Coalesce(list ...interface{}) Expr {
+	if len(list) == 0 {
+		return expr{err: methodErr("Coalesce", "参数不能为空")}
+	}
+	sqls, args, err := buildExprs(list)
+	return expr{sql: "COALESCE(" + strings.Join(sqls, COMMA+SPACE) + ")", args: args, err: err}
+}
+
+/**
+值为 NULL 时返回默认值，格式：IfNull(Col("nickname"), "匿名")
+*/
+func IfNull(value, def interface{}) Expr {
+	sqls, args, err := buildExprs([]interface{}{value, def})
+	return expr{sql: dialect.IfNull(sqls[0], sqls[1]), args: args, err: err}
+}
+
+/**
+拼接字符串，格式：Concat(Col("first_name"), " ", Col("last_name"))
+*/
+func Concat(list ...interface{}) Expr {
+	if len(list) == 0 {
+		return expr{err: methodErr("Concat", "参数不能为空")}
+	}
+	sqls, args, err := buildExprs(list)
+	return expr{sql: dialect.Concat(sqls), args: args, err: err}
+}
+
+//CASE WHEN 支持的条件符号
+var caseOperators = map[string]bool{
+	"=":       true,
+	"<>":      true,
+	"!=":      true,
+	">":       true,
+	">=":      true,
+	"<":       true,
+	"<=":      true,
+	LIKE:      true,
+	NOT_LIKE:  true,
+	IS_NULL:   true,
+	NOT_NULL:  true,
+	NULL_SAFE: true,
+}
+
+/**
+CASE 表达式，格式：Case().When("sex", "=", 1, "男").When("sex", "=", 2, "女").Else("未知")
+*/
+type CaseExpr struct {
+	sql  strings.Builder
+	args []interface{}
+	errs []error
+	when bool
+}
+
+func Case() *CaseExpr {
+	c := &CaseExpr{}
+	c.sql.WriteString("CASE")
+	return c
+}
+
+/**
+添加 WHEN field operator condition THEN then
+field 字段
+operator 条件符号，IS NULL、IS NOT NULL 时忽略 condition
+condition 条件值，可为 Expr，如 Col("other_field")
+then 结果值，可为 Expr
+*/
+func (c *CaseExpr) When(field, operator string, condition, then interface{}) *CaseExpr {
+	if c == nil {
+		return c
+	}
+	operator = normalizeOperator(operator)
+	if !caseOperators[operator] {
+		c.errs = append(c.errs, methodErr("When", "不支持的条件符号 %q", operator))
+	}
+	list := []interface{}{Col(field)}
+	if operator != IS_NULL && operator != NOT_NULL {
+		list = append(list, condition)
+	}
+	list = append(list, then)
+	sqls, args, err := buildExprs(list)
+	if err != nil {
+		c.errs = append(c.errs, err)
+	}
+	c.sql.WriteString(" WHEN " + sqls[0] + SPACE + operator)
+	if len(sqls) == 3 {
+		c.sql.WriteString(SPACE + sqls[1])
+	}
+	c.sql.WriteString(" THEN " + sqls[len(sqls)-1])
+	c.args = append(c.args, args...)
+	c.when = true
+	return c
+}
+
+/**
+添加 ELSE value
+*/
+func (c *CaseExpr) Else(value interface{}) *CaseExpr {
+	if c == nil {
+		return c
+	}
+	sqls, args, err := buildExprs([]interface{}{value})
+	if err != nil {
+		c.errs = append(c.errs, err)
+	}
+	c.sql.WriteString(" ELSE " + sqls[0])
+	c.args = append(c.args, args...)
+	return c
+}
+
+//nil 的 *CaseExpr 作为 Expr 时不等于 nil，需在此返回错误
+func (c *CaseExpr) build() (string, []interface{}, error) {
+	if c == nil {
+		return "", nil, methodErr("Case", "表达式不能为空")
+	}
+	errs := c.errs
+	if !c.when {
+		errs = append(errs[:len(errs):len(errs)], methodErr("Case", "缺少 When 条件"))
+	}
+	return c.sql.String() + " END", c.args, errors.Join(errs...)
+}
+
+/**
+表达式作为查询字段，格式：SelectExpr(IfNull(Col("nickname"), "匿名"), "nickname")
+e 表达式
+alias 别名
+*/
+func (db *Db) SelectExpr(e Expr, alias string) *Db {
+	if e == nil {
+		db.pushErr(methodErr("SelectExpr", "表达式不能为空"))
+		return db
+	}
+	quoted, ok := quoteIdent(alias)
+	if !ok {
+		db.pushErr(methodErr("SelectExpr", "非法别名 %q", alias))
+	}
+	sqlStr, args, err := e.build()
+	if err != nil {
+		db.pushErr(methodErr("SelectExpr", "%w", err))
+	}
+	db.fields = append(db.fields, fieldExpr(sqlStr+SPACE+AS+SPACE+quoted, args))
+	return db
+}
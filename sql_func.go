@@ -3,8 +3,7 @@ package corm
 import (
 	"bytes"
 	"database/sql"
-	"github.com/pkg/errors"
-	"strings"
+	"errors"
 )
 
 /**
@@ -14,15 +13,17 @@ scan 结果绑定参数
 */
 func (db *Db) queryRow(query string, args []interface{}, scan ...interface{}) error {
 	defer db.putPool()
-	if db.getErr() != nil {
-		return db.getErr()
+	if err := db.getErr(); err != nil {
+		return err
 	}
+	query, _ = bindPlaceholders(query)
+	logSql(query, args)
 
 	if db.tx != nil {
 		defer db.clear()
-		return db.tx.QueryRow(query, args...).Scan(scan...)
+		return translateErr(db.tx.QueryRow(query, args...).Scan(scan...))
 	}
-	return db.conn.QueryRow(query, args...).Scan(scan...)
+	return translateErr(db.conn.QueryRow(query, args...).Scan(scan...))
 }
 
 /**
@@ -31,15 +32,22 @@ args 查询参数
 */
 func (db *Db) query(query string, args ...interface{}) (*sql.Rows, error) {
 	defer db.putPool()
-	if db.getErr() != nil {
-		return nil, db.getErr()
+	if err := db.getErr(); err != nil {
+		return nil, err
 	}
+	query, _ = bindPlaceholders(query)
+	logSql(query, args)
+
+	var rows *sql.Rows
+	var err error
 
 	if db.tx != nil {
 		defer db.clear()
-		return db.tx.Query(query, args...)
+		rows, err = db.tx.Query(query, args...)
+	} else {
+		rows, err = db.conn.Query(query, args...)
 	}
-	return db.conn.Query(query, args...)
+	return rows, translateErr(err)
 }
 
 /**
@@ -48,9 +56,11 @@ args 查询参数
 */
 func (db *Db) exec(sqlStr string, args ...interface{}) (sql.Result, error) {
 	defer db.putPool()
-	if db.getErr() != nil {
-		return nil, db.getErr()
+	if err := db.getErr(); err != nil {
+		return nil, err
 	}
+	sqlStr, _ = bindPlaceholders(sqlStr)
+	logSql(sqlStr, args)
 
 	var stmt *sql.Stmt
 	var err error
@@ -63,31 +73,17 @@ func (db *Db) exec(sqlStr string, args ...interface{}) (sql.Result, error) {
 	}
 
 	if err != nil {
-		return nil, err
+		return nil, translateErr(err)
 	}
 	defer stmt.Close()
 
 	rest, err := stmt.Exec(args...)
 	if err != nil {
-		return nil, err
+		return nil, translateErr(err)
 	}
 	return rest, nil
 }
 
-func errs(err error) error {
-	if err != nil {
-		//数据库记录不存在，报此错，可忽略
-		if err == sql.ErrNoRows {
-			return nil
-		}
-		//字段值为NULL时，报此错，可忽略，默认为类型的零值
-		if strings.Index(err.Error(), "sql: Scan error on column index") != -1 {
-			return nil
-		}
-	}
-	return err
-}
-
 func (db *Db) pushErr(err error) {
 	if err != nil {
 		db.err = append(db.err, err)
@@ -95,26 +91,25 @@ func (db *Db) pushErr(err error) {
 	}
 }
 
+//返回构造条件时记录的错误及校验出的错误，多个错误合并返回
 func (db *Db) getErr() error {
-	if len(db.err) > 0 {
-		return db.err[0]
-	}
-	return nil
+	all := append(db.err[:len(db.err):len(db.err)], db.validate()...)
+	return errors.Join(all...)
 }
 
 func (db *Db) check() {
-	if db.table == "" {
-		db.pushErr(errors.New("未定义数据表"))
-	}
 	db.buffer = bytes.Buffer{}
+	db.args = nil
 }
 
 //同一个实例多次调用，清除条件
 func (db *Db) clear() {
 	//*db = Db{conn: db.conn, tx: db.tx}
-	db.table, db.sum, db.count, db.max, db.min = "", "", "", "", ""
-	db.join, db.fields, db.where, db.whereRaw, db.orderBy, db.groupBy, db.having, db.insert, db.update, db.err, db.tx = nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil
-	db.limit, db.offset = 0, 0
+	db.table, db.force, db.sum, db.count, db.max, db.min, db.lock, db.wait = "", "", "", "", "", "", "", ""
+	db.join, db.fields, db.where, db.orderBy, db.groupBy, db.having, db.insert, db.update, db.err, db.tx, db.args = nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil
+	db.scope, db.retry, db.raw, db.distinct, db.tabSub, db.unions, db.ctes, db.windows = nil, nil, false, false, nil, nil, nil, nil
+	db.limit, db.offset, db.attempt = 0, 0, 0
+	db.base = unionBase{}
 	db.buffer = bytes.Buffer{}
 }
 
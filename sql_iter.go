@@ -0,0 +1,80 @@
+package corm
+
+import (
+	"database/sql"
+	"iter"
+)
+
+/**
+以迭代器方式查询多条数据，格式：
+for rows, err := range Tab("users").Select("name", "age").Rows() {
+	if err != nil {
+		return err
+	}
+	user := new(Users)
+	if err := rows.Scan(&user.Name, &user.Age); err != nil {
+		return err
+	}
+}
+遍历时才执行查询，只能遍历一次；break 提前退出时自动关闭 rows
+查询出错或遍历结束后 rows.Err() 不为空时，最后一次迭代返回 nil 和错误
+*/
+func (db *Db) Rows() iter.Seq2[*sql.Rows, error] {
+	return func(yield func(*sql.Rows, error) bool) {
+		rows, err := db.query(db.whereToSql(), db.getWhereValue()...)
+		yieldRows(rows, err, yield)
+	}
+}
+
+/**
+以迭代器方式查询多条数据，用法同 Db.Rows
+*/
+func (r *Raw) Rows() iter.Seq2[*sql.Rows, error] {
+	return func(yield func(*sql.Rows, error) bool) {
+		rows, err := r.db().query(r.sql, r.args...)
+		yieldRows(rows, err, yield)
+	}
+}
+
+func yieldRows(rows *sql.Rows, err error, yield func(*sql.Rows, error) bool) {
+	if errs(err) != nil {
+		yield(nil, err)
+		return
+	}
+	if rows == nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if !yield(rows, nil) {
+			return
+		}
+	}
+	if err := rows.Err(); err != nil {
+		yield(nil, translateErr(err))
+	}
+}
+
+/**
+查询多条数据并逐行转换为 T，任一行出错时返回该错误，格式：
+list, err := All(Tab("users").Select("name", "age"), func(rows *sql.Rows) (*Users, error) {
+	user := new(Users)
+	err := rows.Scan(&user.Name, &user.Age)
+	return user, err
+})
+*/
+func All[T any](db *Db, scan func(rows *sql.Rows) (T, error)) ([]T, error) {
+	var list []T
+	for rows, err := range db.Rows() {
+		if err != nil {
+			return nil, err
+		}
+		item, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+	}
+	return list, nil
+}
@@ -0,0 +1,38 @@
+package corm
+
+import "sync"
+
+/**
+日志接口，*log.Logger 可直接使用
+*/
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+var (
+	logger   Logger
+	loggerMu sync.RWMutex
+)
+
+/**
+设置日志，传 nil 关闭日志
+*/
+func SetLogger(l Logger) {
+	loggerMu.Lock()
+	logger = l
+	loggerMu.Unlock()
+}
+
+func logf(format string, v ...interface{}) {
+	loggerMu.RLock()
+	l := logger
+	loggerMu.RUnlock()
+	if l != nil {
+		l.Printf(format, v...)
+	}
+}
+
+//记录执行的SQL语句及参数
+func logSql(sqlStr string, args []interface{}) {
+	logf("corm: %s %v", sqlStr, args)
+}
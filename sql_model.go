@@ -5,45 +5,85 @@ import (
 	"database/sql"
 )
 
+type field struct {
+	expr string
+	args []interface{}
+	sub  *Db
+}
+
+func fieldExpr(expr string, args []interface{}) field {
+	return field{expr: expr, args: args}
+}
+
 type where struct {
+	method         string
+	raw            string
 	field          string
 	operator       string
 	condition      interface{}
 	conditionArray []interface{}
+	column         string
+	sub            *Db
+	group          []where
+	or             bool
+}
+
+type cte struct {
+	name      string
+	columns   []string
+	sub       *Db
+	recursive *Db
+}
+
+type union struct {
+	all bool
+	db  *Db
 }
 
-type having struct {
-	field     string
-	operator  string
-	condition interface{}
+//Union 前调用的 OrderBy、Limit、Offset，作用于基础查询
+type unionBase struct {
+	orderBy []orderBy
+	limit   int
+	offset  int
 }
 
 type join struct {
 	table     string
 	direction string
 	on        string
+	onWhere   []where
 }
 
 type orderBy struct {
-	field string
-	by    string
+	method string
+	field  string
+	by     string
 }
 
 type Db struct {
 	conn     *sql.DB
 	tx       *sql.Tx
+	scope    *transScope
+	retry    *RetryPolicy
+	attempt  int
 	err      []error
 	table    string
+	tabSub   *Db
 	force    string
 	join     []join
-	fields   []string
+	fields   []field
 	where    []where
-	whereRaw []string
 	orderBy  []orderBy
 	groupBy  []string
 	limit    int
 	offset   int
-	having   []having
+	having   []where
+	unions   []union
+	base     unionBase
+	ctes     []cte
+	windows  []window
+	lock     string
+	wait     string
 	sum      string
 	count    string
 	max      string
@@ -51,5 +91,8 @@ type Db struct {
 	insert   map[string]interface{}
 	update   map[string]interface{}
 	compose  []string
+	raw      bool
+	distinct bool
 	buffer   bytes.Buffer
+	args     []interface{}
 }
@@ -0,0 +1,141 @@
+package corm
+
+import (
+	"fmt"
+	"strings"
+)
+
+/**
+参数为 map[string]interface{} 时按命名参数绑定，否则原样返回
+*/
+func (db *Db) bindArgs(method, sqlStr string, args []interface{}) (string, []interface{}) {
+	if len(args) != 1 {
+		return sqlStr, args
+	}
+	params, ok := args[0].(map[string]interface{})
+	if !ok {
+		return sqlStr, args
+	}
+	bound, vals, err := bindNamed(sqlStr, params)
+	if err != nil {
+		db.pushErr(methodErr(method, "%v", err))
+		return sqlStr, nil
+	}
+	return bound, vals
+}
+
+/**
+将 :name 命名参数替换为方言的占位符，按出现顺序返回参数
+同一参数可出现多次，切片参数展开为多个占位符，如 IN (:ids) 展开为 IN (?, ?, ?)
+*/
+func bindNamed(sqlStr string, params map[string]interface{}) (string, []interface{}, error) {
+	var vals []interface{}
+	bound, err := replaceNamed(sqlStr, func(name string) (string, error) {
+		value, ok := params[name]
+		if !ok {
+			return "", fmt.Errorf("缺少命名参数 :%s", name)
+		}
+		list, isList := expandList(value)
+		if !isList {
+			vals = append(vals, value)
+			return QUES, nil
+		}
+		if len(list) == 0 {
+			return "", fmt.Errorf("命名参数 :%s 不能为空切片", name)
+		}
+		places := make([]string, len(list))
+		for k := range places {
+			places[k] = QUES
+		}
+		vals = append(vals, list...)
+		return strings.Join(places, ", "), nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return bound, vals, nil
+}
+
+/**
+按顺序替换SQL中的 :name 命名参数，引号中的内容、:: 及 := 不做处理
+*/
+func replaceNamed(sqlStr string, replace func(name string) (string, error)) (string, error) {
+	var buf strings.Builder
+	var quote byte
+	for i := 0; i < len(sqlStr); i++ {
+		c := sqlStr[i]
+		if quote != 0 {
+			buf.WriteByte(c)
+			if c == '\\' && quote != '`' && i+1 < len(sqlStr) {
+				i++
+				buf.WriteByte(sqlStr[i])
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+			buf.WriteByte(c)
+		case c == ':' && i+1 < len(sqlStr) && (sqlStr[i+1] == ':' || sqlStr[i+1] == '='):
+			buf.WriteString(sqlStr[i : i+2])
+			i++
+		case c == ':' && i+1 < len(sqlStr) && isNameStart(sqlStr[i+1]):
+			j := i + 1
+			for j < len(sqlStr) && isNamePart(sqlStr[j]) {
+				j++
+			}
+			place, err := replace(sqlStr[i+1 : j])
+			if err != nil {
+				return "", err
+			}
+			buf.WriteString(place)
+			i = j - 1
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String(), nil
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNamePart(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
+
+//展开常用类型的切片，[]byte 作为单个参数
+func expandList(value interface{}) ([]interface{}, bool) {
+	switch list := value.(type) {
+	case []interface{}:
+		return list, true
+	case []string:
+		return toInterfaces(len(list), func(i int) interface{} { return list[i] }), true
+	case []int:
+		return toInterfaces(len(list), func(i int) interface{} { return list[i] }), true
+	case []int32:
+		return toInterfaces(len(list), func(i int) interface{} { return list[i] }), true
+	case []int64:
+		return toInterfaces(len(list), func(i int) interface{} { return list[i] }), true
+	case []uint:
+		return toInterfaces(len(list), func(i int) interface{} { return list[i] }), true
+	case []uint32:
+		return toInterfaces(len(list), func(i int) interface{} { return list[i] }), true
+	case []uint64:
+		return toInterfaces(len(list), func(i int) interface{} { return list[i] }), true
+	case []float64:
+		return toInterfaces(len(list), func(i int) interface{} { return list[i] }), true
+	}
+	return nil, false
+}
+
+func toInterfaces(length int, get func(i int) interface{}) []interface{} {
+	list := make([]interface{}, length)
+	for i := range list {
+		list[i] = get(i)
+	}
+	return list
+}
@@ -0,0 +1,303 @@
+package corm
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/**
+分页结果，不统计总数时 Total、Pages 为 -1
+*/
+type Page struct {
+	Page     int
+	PageSize int
+	Total    int64
+	Pages    int64
+	HasNext  bool
+	HasPrev  bool
+	//Total 是否为估算值
+	Approximate bool
+}
+
+/**
+扫描当前行，参数与 rows.Scan 相同
+*/
+type Scanner func(dest ...interface{}) error
+
+const (
+	countExact = iota
+	countNone
+	countWindow
+	countApprox
+)
+
+type pageOptions struct {
+	count int
+}
+
+type PageOption func(o *pageOptions)
+
+/**
+不统计总数，多查询一条判断是否有下一页
+*/
+func WithoutCount() PageOption {
+	return func(o *pageOptions) {
+		o.count = countNone
+	}
+}
+
+/**
+使用 COUNT(*) OVER() 在数据查询中同时统计总数，只查询一次，需 MySQL 8.0 及以上
+当前页没有数据时（超出最后一页）会再执行一次 Count，不能用于 Distinct、Union 查询
+*/
+func WithWindowCount() PageOption {
+	return func(o *pageOptions) {
+		o.count = countWindow
+	}
+}
+
+/**
+估算总数，用于数据量很大、不需要精确总数的表，Page.Approximate 为 true
+单表且没有条件时使用 information_schema.TABLES 的 TABLE_ROWS，否则使用 EXPLAIN 估算，关联查询按各表估算行数相乘
+*/
+func WithApproxCount() PageOption {
+	return func(o *pageOptions) {
+		o.count = countApprox
+	}
+}
+
+/**
+分页查询，格式：
+page, err := Tab("users").Select("name", "age").OrderBy("id", "desc").Paginate(1, 20, func(scan Scanner) error {
+	user := new(Users)
+	err := scan(&user.Name, &user.Age)
+	list = append(list, user)
+	return err
+}, WithWindowCount())
+默认先执行 Count 统计总数，超出最后一页时不查询数据
+page 页数，从 1 开始
+size 每页记录数
+callable 每行调用一次，使用 scan 扫描数据
+opts 统计方式：WithoutCount、WithWindowCount、WithApproxCount
+*/
+func (db *Db) Paginate(page, size int, callable func(scan Scanner) error, opts ...PageOption) (*Page, error) {
+	o := pageOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if page < 1 {
+		db.pushErr(methodErr("Paginate", "页数必须大于 0：%d", page))
+	}
+	if size < 1 {
+		db.pushErr(methodErr("Paginate", "每页记录数必须大于 0：%d", size))
+	}
+	if o.count == countWindow && (db.distinct || len(db.unions) > 0) {
+		db.pushErr(methodErr("Paginate", "WithWindowCount 不能用于 Distinct、Union 查询"))
+	}
+	if err := db.getErr(); err != nil {
+		db.putPool()
+		return nil, err
+	}
+
+	p := &Page{Page: page, PageSize: size, Total: -1, Pages: -1, HasPrev: page > 1}
+	db.offset = (page - 1) * size
+	switch o.count {
+	case countNone:
+		db.limit = size + 1
+		n, err := db.pageRows(size, callable)
+		if err != nil {
+			return nil, err
+		}
+		p.HasNext = n > size
+		return p, nil
+
+	case countWindow:
+		countDb := db.countDb()
+		var total int64
+		if len(db.fields) == 0 {
+			db.fields = []field{fieldExpr("*", nil)}
+		}
+		db.fields = append(db.fields[:len(db.fields):len(db.fields)], fieldExpr("COUNT(*) OVER() AS corm_total", nil))
+		db.limit = size
+		n, err := db.pageRows(size, callable, &total)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 && page > 1 {
+			if total, err = countDb.Count(); err != nil {
+				return nil, err
+			}
+		}
+		p.setTotal(total)
+		return p, nil
+
+	case countApprox:
+		total, err := db.clone().approxCount()
+		if err != nil {
+			return nil, err
+		}
+		p.Approximate = true
+		p.setTotal(total)
+
+	default:
+		total, err := db.countDb().Count()
+		if err != nil {
+			return nil, err
+		}
+		p.setTotal(total)
+	}
+
+	if int64(page) > p.Pages {
+		db.putPool()
+		return p, nil
+	}
+	db.limit = size
+	if _, err := db.pageRows(size, callable); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+//克隆统计总数的查询，不带分页的 Limit、Offset
+func (db *Db) countDb() *Db {
+	countDb := db.clone()
+	countDb.limit, countDb.offset = 0, 0
+	return countDb
+}
+
+func (p *Page) setTotal(total int64) {
+	p.Total = total
+	p.Pages = (total + int64(p.PageSize) - 1) / int64(p.PageSize)
+	p.HasNext = int64(p.Page) < p.Pages
+}
+
+//查询当前页数据，最多回调 size 次，返回查询到的行数；extra 为每行追加扫描的字段
+func (db *Db) pageRows(size int, callable func(scan Scanner) error, extra ...interface{}) (int, error) {
+	rows, err := db.query(db.whereToSql(), db.getWhereValue()...)
+	if errs(err) != nil {
+		return 0, err
+	}
+	if rows == nil {
+		return 0, nil
+	}
+	defer rows.Close()
+
+	scan := func(dest ...interface{}) error {
+		return rows.Scan(append(dest[:len(dest):len(dest)], extra...)...)
+	}
+	n := 0
+	for rows.Next() {
+		n++
+		if n > size {
+			break
+		}
+		if err := callable(scan); err != nil {
+			return n, err
+		}
+	}
+	return n, translateErr(rows.Err())
+}
+
+//估算查询的行数：单表且没有条件时使用 information_schema.TABLES 的 TABLE_ROWS，否则使用 EXPLAIN
+func (db *Db) approxCount() (int64, error) {
+	db.orderBy, db.limit, db.offset = nil, 0, 0
+	if schema, table, ok := db.plainTable(); ok {
+		total, found, err := db.tableRows(schema, table)
+		if err != nil || found {
+			return total, err
+		}
+	}
+	return db.explainRows()
+}
+
+//单表且没有条件、分组、去重、联合时返回库名和表名
+func (db *Db) plainTable() (schema, table string, ok bool) {
+	if db.table == "" || db.tabSub != nil || len(db.join) > 0 || len(db.where) > 0 || len(db.groupBy) > 0 ||
+		len(db.having) > 0 || len(db.unions) > 0 || len(db.ctes) > 0 || db.distinct {
+		return "", "", false
+	}
+	name := strings.Fields(db.table)[0]
+	segs := strings.Split(name, ".")
+	for k, seg := range segs {
+		segs[k] = strings.ReplaceAll(strings.Trim(seg, "`"), "``", "`")
+	}
+	if len(segs) == 2 {
+		return segs[0], segs[1], true
+	}
+	return "", segs[0], len(segs) == 1
+}
+
+//查询 information_schema 中表的估算行数，视图等没有行数时 found 为 false
+func (db *Db) tableRows(schema, table string) (total int64, found bool, err error) {
+	sqlStr := "SELECT TABLE_ROWS FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?"
+	args := []interface{}{table}
+	if schema != "" {
+		sqlStr = "SELECT TABLE_ROWS FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?"
+		args = []interface{}{schema, table}
+	}
+	var rows sql.NullInt64
+	err = db.Raw(sqlStr, args...).First(&rows)
+	if errors.Is(err, ErrNotFound) {
+		return 0, false, nil
+	}
+	return rows.Int64, rows.Valid, errs(err)
+}
+
+/**
+使用 EXPLAIN 估算查询的行数：第一个 SELECT 中各表的 rows × filtered% 相乘，即关联后的估算行数
+只是优化器的估算，GROUP BY、DISTINCT 按分组前的行数估算，UNION 只估算第一个查询，可能与实际相差较大
+*/
+func (db *Db) explainRows() (int64, error) {
+	sqlStr := db.whereToSql()
+	args := db.getWhereValue()
+
+	var total float64
+	var selectId string
+	found := false
+	err := db.Raw("EXPLAIN "+sqlStr, args...).Query(func(rows *sql.Rows) error {
+		columns, err := rows.Columns()
+		if err != nil {
+			return err
+		}
+		values := make([]sql.RawBytes, len(columns))
+		dest := make([]interface{}, len(columns))
+		for k := range values {
+			dest[k] = &values[k]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		var id string
+		estimate, filtered := -1.0, 100.0
+		for k, column := range columns {
+			switch strings.ToLower(column) {
+			case "id":
+				id = string(values[k])
+			case "rows":
+				if n, err := strconv.ParseFloat(string(values[k]), 64); err == nil {
+					estimate = n
+				}
+			case "filtered":
+				if n, err := strconv.ParseFloat(string(values[k]), 64); err == nil {
+					filtered = n
+				}
+			}
+		}
+		//rows 为 NULL 的行不参与计算；子查询、UNION 的 id 与第一个 SELECT 不同，不参与计算
+		if estimate < 0 || (found && id != selectId) {
+			return nil
+		}
+		if !found {
+			total, selectId, found = 1, id, true
+		}
+		total *= estimate * filtered / 100
+		return nil
+	})
+	if !found {
+		return 0, err
+	}
+	return int64(math.Round(total)), err
+}
@@ -0,0 +1,201 @@
+package corm
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+//已加载的命名查询
+var (
+	queries   = map[string]string{}
+	queriesMu sync.RWMutex
+)
+
+/**
+加载目录（含子目录）下 .sql 文件中的命名查询，格式：
+-- name: monthly_revenue
+SELECT ... WHERE created_at >= :start_date
+每个查询以 -- name: 开头，到下一个 -- name: 或文件末尾结束，参数使用 :name 命名参数
+没有 -- name: 标注的文件会被跳过，查询名重复、查询为空、使用 ? 占位符时返回错误
+dir 目录
+*/
+func LoadQueries(dir string) error {
+	return LoadQueriesFS(os.DirFS(dir), ".")
+}
+
+/**
+从 fs.FS 加载命名查询，可传入 embed.FS
+fsys 文件系统
+dir 目录
+*/
+func LoadQueriesFS(fsys fs.FS, dir string) error {
+	loaded := make(map[string]string)
+	err := fs.WalkDir(fsys, dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(file) != ".sql" {
+			return nil
+		}
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		//没有标注的文件（如建表语句）直接跳过
+		if !hasQueryName(string(content)) {
+			return nil
+		}
+		parsed, err := parseQueries(string(content))
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		for _, q := range parsed {
+			if _, ok := loaded[q.name]; ok {
+				return fmt.Errorf("%s: 查询 %s 重复定义", file, q.name)
+			}
+			loaded[q.name] = q.sql
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	queriesMu.Lock()
+	defer queriesMu.Unlock()
+	for name := range loaded {
+		if _, ok := queries[name]; ok {
+			return fmt.Errorf("查询 %s 重复定义", name)
+		}
+	}
+	for name, sqlStr := range loaded {
+		queries[name] = sqlStr
+	}
+	return nil
+}
+
+/**
+执行命名查询，格式：NamedQuery("monthly_revenue", map[string]interface{}{"start_date": start}).Get(...)
+与 Raw 相同，在事务中调用时使用当前事务
+name 查询名
+params 命名参数
+*/
+func (db *Db) NamedQuery(name string, params map[string]interface{}) *Raw {
+	queriesMu.RLock()
+	sqlStr, ok := queries[name]
+	queriesMu.RUnlock()
+	if !ok {
+		raw := db.Raw("")
+		raw.err = append(raw.err, methodErr("NamedQuery", "查询 %s 未定义", name))
+		return raw
+	}
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	return db.Raw(sqlStr, params)
+}
+
+type namedQuery struct {
+	name string
+	sql  string
+}
+
+//解析文件中的命名查询
+func parseQueries(content string) ([]namedQuery, error) {
+	var list []namedQuery
+	var current *namedQuery
+	var body strings.Builder
+
+	finish := func() error {
+		if current == nil {
+			return nil
+		}
+		//去掉末尾的注释行及分号
+		lines := strings.Split(strings.TrimSpace(body.String()), "\n")
+		for len(lines) > 0 {
+			last := strings.TrimSpace(lines[len(lines)-1])
+			if last != "" && !strings.HasPrefix(last, "--") {
+				break
+			}
+			lines = lines[:len(lines)-1]
+		}
+		current.sql = strings.TrimSpace(strings.TrimRight(strings.Join(lines, "\n"), "; \t"))
+		if err := checkQuery(*current); err != nil {
+			return err
+		}
+		list = append(list, *current)
+		body.Reset()
+		return nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		trimmed := strings.TrimSpace(text)
+		if name, ok := queryName(trimmed); ok {
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			if name == "" {
+				return nil, fmt.Errorf("第 %d 行：查询名不能为空", line)
+			}
+			current = &namedQuery{name: name}
+			continue
+		}
+		if current == nil {
+			if trimmed != "" && !strings.HasPrefix(trimmed, "--") {
+				return nil, fmt.Errorf("第 %d 行：SQL 语句前缺少 -- name: 标注", line)
+			}
+			continue
+		}
+		body.WriteString(text)
+		body.WriteString("\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+//解析 -- name: xxx 标注
+func queryName(line string) (string, bool) {
+	if !strings.HasPrefix(line, "--") {
+		return "", false
+	}
+	rest := strings.TrimSpace(strings.TrimPrefix(line, "--"))
+	if !strings.HasPrefix(rest, "name:") {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimPrefix(rest, "name:")), true
+}
+
+func hasQueryName(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		if _, ok := queryName(strings.TrimSpace(line)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+//校验查询：不能为空、不能使用 ? 占位符
+func checkQuery(q namedQuery) error {
+	if q.sql == "" {
+		return fmt.Errorf("查询 %s 为空", q.name)
+	}
+	if countPlaceholders(q.sql) > 0 {
+		return fmt.Errorf("查询 %s 请使用 :name 命名参数代替 ? 占位符", q.name)
+	}
+	return nil
+}
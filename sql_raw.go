@@ -0,0 +1,106 @@
+package corm
+
+import "database/sql"
+
+/**
+原生SQL语句，与构造器共用执行、日志及事务
+每次执行都使用新的 Db，可重复执行
+*/
+type Raw struct {
+	conn  *sql.DB
+	tx    *sql.Tx
+	scope *transScope
+	err   []error
+	sql   string
+	args  []interface{}
+}
+
+/**
+原生SQL语句，格式：Raw("SELECT name FROM users WHERE id = ?", 10)
+在事务中调用时使用当前事务
+sqlStr SQL语句，用户输入必须通过占位符绑定
+args 占位符对应的参数，传入 map[string]interface{} 时使用 :name 命名参数
+*/
+func (db *Db) Raw(sqlStr string, args ...interface{}) *Raw {
+	r := &Raw{conn: db.conn, tx: db.tx, scope: db.scope}
+	newDB := r.db()
+	defer newDB.putPool()
+	sqlStr, args = newDB.bindArgs("Raw", sqlStr, args)
+	if n := countPlaceholders(sqlStr); n != len(args) {
+		newDB.pushErr(methodErr("Raw", "占位符数量 %d 与参数数量 %d 不一致", n, len(args)))
+	}
+	r.err, r.sql, r.args = append([]error(nil), newDB.err...), sqlStr, args
+	return r
+}
+
+//执行使用的 Db，执行后放回池中
+func (r *Raw) db() *Db {
+	newDB := dbPool.Get().(*Db)
+	newDB.conn = r.conn
+	newDB.tx = r.tx
+	newDB.scope = r.scope
+	newDB.raw = true
+	newDB.err = append([]error(nil), r.err...)
+	return newDB
+}
+
+/**
+查询一条数据
+*/
+func (r *Raw) First(result ...interface{}) error {
+	err := r.db().queryRow(r.sql, r.args, result...)
+	if errs(err) != nil {
+		return err
+	}
+	return nil
+}
+
+/**
+查询多条数据
+callable 回调函数
+*/
+func (r *Raw) Get(callable func(rows *sql.Rows)) error {
+	rows, err := r.db().query(r.sql, r.args...)
+	if errs(err) != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		callable(rows)
+	}
+	return translateErr(rows.Err())
+}
+
+/**
+查询多条数据，回调返回错误时停止
+callable 回调函数
+*/
+func (r *Raw) Query(callable func(rows *sql.Rows) error) error {
+	rows, err := r.db().query(r.sql, r.args...)
+	if errs(err) != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err = callable(rows)
+		if err != nil {
+			return err
+		}
+	}
+	return translateErr(rows.Err())
+}
+
+/**
+执行语句，返回执行结果
+*/
+func (r *Raw) Exec() (sql.Result, error) {
+	return r.db().exec(r.sql, r.args...)
+}
+
+//统计SQL中按方言编号的占位符数量，忽略引号中的内容
+func countPlaceholders(sqlStr string) int {
+	_, count := bindPlaceholders(sqlStr)
+	return count
+}
@@ -0,0 +1,95 @@
+package corm
+
+import (
+	"database/sql"
+	"slices"
+	"strings"
+)
+
+/**
+单条记录，由 cormgen 生成，用于 FindOne
+*/
+type RowScanner interface {
+	//查询字段，顺序与 ScanRow 一致
+	Columns() []string
+	//扫描当前行
+	ScanRow(rows *sql.Rows) error
+}
+
+/**
+记录列表，由 cormgen 生成，用于 Find
+*/
+type ListScanner interface {
+	//查询字段，顺序与 ScanAppend 一致
+	Columns() []string
+	//扫描当前行并追加到列表
+	ScanAppend(rows *sql.Rows) error
+}
+
+/**
+查询多条数据到 cormgen 生成的列表类型，不使用反射，格式：
+var list UsersList
+err := Tab(UsersTable).Where("age", ">", 20).Find(&list)
+未调用 Select 时查询 list.Columns()，调用 Select 时字段名称、顺序需与 Columns() 一致，否则返回错误
+*/
+func (db *Db) Find(list ListScanner) error {
+	if list == nil {
+		db.pushErr(methodErr("Find", "列表不能为空"))
+	} else {
+		db.selectColumns("Find", list.Columns())
+	}
+	return db.Query(func(rows *sql.Rows) error {
+		return list.ScanAppend(rows)
+	})
+}
+
+/**
+查询一条数据到 cormgen 生成的类型，记录不存在时返回 ErrNotFound，格式：
+user := new(Users)
+err := Tab(UsersTable).Where("id", "=", 1).FindOne(user)
+查询字段的要求同 Find
+*/
+func (db *Db) FindOne(m RowScanner) error {
+	if m == nil {
+		db.pushErr(methodErr("FindOne", "数据不能为空"))
+	} else {
+		db.selectColumns("FindOne", m.Columns())
+	}
+	db.limit = 1
+
+	found := false
+	err := db.Query(func(rows *sql.Rows) error {
+		found = true
+		return m.ScanRow(rows)
+	})
+	if err == nil && !found {
+		err = translateErr(sql.ErrNoRows)
+	}
+	return errs(err)
+}
+
+//未调用 Select 时查询 columns，否则校验查询字段与 columns 的名称、顺序一致，避免扫描到错误的字段
+func (db *Db) selectColumns(method string, columns []string) {
+	if len(db.fields) == 0 {
+		db.Select(columns...)
+		return
+	}
+	names := make([]string, len(db.fields))
+	for k, f := range db.fields {
+		names[k] = fieldName(f.expr)
+	}
+	if !slices.Equal(names, columns) {
+		db.pushErr(methodErr(method, "查询字段 %v 与 Columns() %v 不一致", names, columns))
+	}
+}
+
+//查询字段的列名：有别名时为别名，否则为去掉表名的字段名
+func fieldName(expr string) string {
+	parts := strings.Fields(expr)
+	if n := len(parts); n >= 3 && strings.EqualFold(parts[n-2], AS) {
+		expr = parts[n-1]
+	} else if k := strings.LastIndex(expr, "."); k >= 0 {
+		expr = expr[k+1:]
+	}
+	return strings.Trim(strings.TrimSpace(expr), "`\"")
+}
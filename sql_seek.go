@@ -0,0 +1,409 @@
+package corm
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/**
+游标无效：格式错误、签名不匹配或与排序字段不一致
+*/
+var ErrInvalidCursor = errors.New("游标无效")
+
+var (
+	cursorSecret   []byte
+	cursorSecretMu sync.RWMutex
+)
+
+func init() {
+	cursorSecret = make([]byte, 32)
+	if _, err := rand.Read(cursorSecret); err != nil {
+		panic(err)
+	}
+}
+
+/**
+设置游标签名密钥，默认在启动时随机生成，多实例部署或重启后需继续使用游标时请设置相同的密钥
+*/
+func SetCursorSecret(secret []byte) {
+	cursorSecretMu.Lock()
+	cursorSecret = append([]byte(nil), secret...)
+	cursorSecretMu.Unlock()
+}
+
+/**
+游标分页的排序字段，由 SeekAsc、SeekDesc 创建
+*/
+type SeekColumn struct {
+	column string
+	desc   bool
+}
+
+/**
+升序排序字段
+*/
+func SeekAsc(column string) SeekColumn {
+	return SeekColumn{column: column}
+}
+
+/**
+降序排序字段
+*/
+func SeekDesc(column string) SeekColumn {
+	return SeekColumn{column: column, desc: true}
+}
+
+/**
+游标分页结果，Next、Prev 为下一页、上一页的游标，没有时为空
+*/
+type SeekResult struct {
+	Next    string
+	Prev    string
+	HasNext bool
+	HasPrev bool
+}
+
+type cursorValue struct {
+	T string `json:"t"`
+	V string `json:"v"`
+}
+
+type cursor struct {
+	//排序字段签名
+	Order string `json:"o"`
+	//是否为上一页
+	Prev bool `json:"p,omitempty"`
+	//排序字段的值
+	Values []cursorValue `json:"v"`
+}
+
+/**
+游标分页（seek 分页），按排序字段的值定位，不使用 OFFSET，不统计总数，格式：
+Tab("orders").Select("id", "amount", "created_at").SeekPage([]SeekColumn{SeekDesc("created_at"), SeekDesc("id")}, cursor, 20,
+	func(rows *sql.Rows) ([]interface{}, error) {
+		o := new(Order)
+		err := rows.Scan(&o.Id, &o.Amount, &o.CreatedAt)
+		list = append(list, o)
+		return []interface{}{o.CreatedAt, o.Id}, err
+	})
+排序字段不能为 NULL，最后一个字段需唯一（如主键），查询上一页时排序字段需包含在查询字段中
+order 排序字段，支持混合排序方向
+after 上一次返回的 Next 或 Prev 游标，第一页传空字符串
+size 每页记录数
+callable 扫描数据并按 order 的顺序返回排序字段的值，支持整数、浮点数、字符串、time.Time
+*/
+func (db *Db) SeekPage(order []SeekColumn, after string, size int, callable func(rows *sql.Rows) ([]interface{}, error)) (*SeekResult, error) {
+	if len(order) == 0 {
+		db.pushErr(methodErr("SeekPage", "排序字段不能为空"))
+	}
+	if size <= 0 {
+		db.pushErr(methodErr("SeekPage", "每页记录数必须大于 0：%d", size))
+	}
+	quoted := make([]string, len(order))
+	for k, o := range order {
+		quoted[k] = db.quoteColumn("SeekPage", o.column)
+	}
+
+	var c cursor
+	if after != "" {
+		var err error
+		c, err = decodeCursor(after, seekSignature(order))
+		if err != nil {
+			db.pushErr(methodErr("SeekPage", "%w", err))
+		} else {
+			condition, args := seekCondition(quoted, order, c.Prev, c.values())
+			db.where = db.groupedWhere("SeekPage")
+			db.pushWhereRaw("SeekPage", condition, args)
+		}
+	}
+
+	result := &SeekResult{}
+	var first, last []interface{}
+	scan := func(rows *sql.Rows) error {
+		keys, err := callable(rows)
+		if err != nil {
+			return err
+		}
+		if len(keys) != len(order) {
+			return methodErr("SeekPage", "返回的排序字段值数量 %d 与排序字段数量 %d 不一致", len(keys), len(order))
+		}
+		if first == nil {
+			first = keys
+		}
+		last = keys
+		return nil
+	}
+
+	var err error
+	if c.Prev {
+		result.HasNext = true
+		result.HasPrev, err = db.seekPrev(order, quoted, size, scan)
+	} else {
+		result.HasPrev = after != ""
+		result.HasNext, err = db.seekNext(order, quoted, size, scan)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if first == nil {
+		return result, nil
+	}
+	if result.HasNext {
+		if result.Next, err = encodeCursor(seekSignature(order), false, last); err != nil {
+			return nil, err
+		}
+	}
+	if result.HasPrev {
+		if result.Prev, err = encodeCursor(seekSignature(order), true, first); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+//查询下一页，多查询一条判断是否还有下一页
+func (db *Db) seekNext(order []SeekColumn, quoted []string, size int, scan func(rows *sql.Rows) error) (bool, error) {
+	db.orderBy = seekOrderBy(order, quoted, false)
+	db.limit = size + 1
+	rows, err := db.query(db.whereToSql(), db.getWhereValue()...)
+	if errs(err) != nil {
+		return false, err
+	}
+	if rows == nil {
+		return false, nil
+	}
+	defer rows.Close()
+
+	for n := 0; rows.Next(); n++ {
+		if n == size {
+			return true, nil
+		}
+		if err := scan(rows); err != nil {
+			return false, err
+		}
+	}
+	return false, translateErr(rows.Err())
+}
+
+//查询上一页：按相反方向取 size 条，外层按原方向排序；再查询第 size+1 条判断是否还有上一页
+func (db *Db) seekPrev(order []SeekColumn, quoted []string, size int, scan func(rows *sql.Rows) error) (bool, error) {
+	db.orderBy = seekOrderBy(order, quoted, true)
+
+	probe := db.clone()
+	probe.fields = []field{fieldExpr("1", nil)}
+	probe.limit, probe.offset = 1, size
+	var one int
+	err := probe.First(&one)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return false, err
+	}
+	hasPrev := one == 1
+
+	db.limit = size
+	outer := db.TabSub(db, "t")
+	for k, o := range order {
+		by := "ASC"
+		if o.desc {
+			by = "DESC"
+		}
+		name := quoted[k][strings.LastIndex(quoted[k], ".")+1:]
+		outer.orderBy = append(outer.orderBy, orderBy{method: "SeekPage", field: name, by: by})
+	}
+	rows, err := outer.query(outer.whereToSql(), outer.getWhereValue()...)
+	if errs(err) != nil {
+		return false, err
+	}
+	if rows == nil {
+		return hasPrev, nil
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return false, err
+		}
+	}
+	return hasPrev, translateErr(rows.Err())
+}
+
+//排序，reverse 为 true 时反向
+func seekOrderBy(order []SeekColumn, quoted []string, reverse bool) []orderBy {
+	list := make([]orderBy, len(order))
+	for k, o := range order {
+		by := "ASC"
+		if o.desc != reverse {
+			by = "DESC"
+		}
+		list[k] = orderBy{method: "SeekPage", field: quoted[k], by: by}
+	}
+	return list
+}
+
+/**
+生成定位条件及参数，排序方向一致时使用 (a, b) < (?, ?)，
+混合方向时展开为 (a > ?) OR (a = ? AND b < ?)
+*/
+func seekCondition(quoted []string, order []SeekColumn, prev bool, values []interface{}) (string, []interface{}) {
+	op := func(o SeekColumn) string {
+		if o.desc != prev {
+			return "<"
+		}
+		return ">"
+	}
+	mixed := false
+	for _, o := range order {
+		if o.desc != order[0].desc {
+			mixed = true
+		}
+	}
+	if !mixed {
+		places := make([]string, len(order))
+		for k := range places {
+			places[k] = QUES
+		}
+		return "(" + strings.Join(quoted, COMMA+SPACE) + ") " + op(order[0]) + " (" + strings.Join(places, COMMA+SPACE) + ")", values
+	}
+
+	var or []string
+	var args []interface{}
+	for i := range order {
+		var and []string
+		for j := 0; j < i; j++ {
+			and = append(and, quoted[j]+" = "+QUES)
+		}
+		and = append(and, quoted[i]+SPACE+op(order[i])+SPACE+QUES)
+		or = append(or, "("+strings.Join(and, SPACE+AND+SPACE)+")")
+		args = append(args, values[:i+1]...)
+	}
+	return strings.Join(or, SPACE+OR+SPACE), args
+}
+
+//游标中排序字段的值，已在 decodeCursor 中校验
+func (c cursor) values() []interface{} {
+	values := make([]interface{}, len(c.Values))
+	for k, v := range c.Values {
+		values[k], _ = v.value()
+	}
+	return values
+}
+
+//排序字段签名，如 created_at:desc,id:desc
+func seekSignature(order []SeekColumn) string {
+	list := make([]string, len(order))
+	for k, o := range order {
+		by := "asc"
+		if o.desc {
+			by = "desc"
+		}
+		list[k] = o.column + ":" + by
+	}
+	return strings.Join(list, ",")
+}
+
+func newCursorValue(value interface{}) (cursorValue, error) {
+	switch v := value.(type) {
+	case int:
+		return cursorValue{T: "i", V: strconv.FormatInt(int64(v), 10)}, nil
+	case int32:
+		return cursorValue{T: "i", V: strconv.FormatInt(int64(v), 10)}, nil
+	case int64:
+		return cursorValue{T: "i", V: strconv.FormatInt(v, 10)}, nil
+	case uint:
+		return cursorValue{T: "u", V: strconv.FormatUint(uint64(v), 10)}, nil
+	case uint32:
+		return cursorValue{T: "u", V: strconv.FormatUint(uint64(v), 10)}, nil
+	case uint64:
+		return cursorValue{T: "u", V: strconv.FormatUint(v, 10)}, nil
+	case float64:
+		return cursorValue{T: "f", V: strconv.FormatFloat(v, 'g', -1, 64)}, nil
+	case string:
+		return cursorValue{T: "s", V: v}, nil
+	case []byte:
+		return cursorValue{T: "s", V: string(v)}, nil
+	case time.Time:
+		return cursorValue{T: "t", V: v.Format(time.RFC3339Nano)}, nil
+	}
+	return cursorValue{}, methodErr("SeekPage", "不支持的排序字段值类型 %T", value)
+}
+
+func (v cursorValue) value() (interface{}, error) {
+	switch v.T {
+	case "i":
+		return strconv.ParseInt(v.V, 10, 64)
+	case "u":
+		return strconv.ParseUint(v.V, 10, 64)
+	case "f":
+		return strconv.ParseFloat(v.V, 64)
+	case "s":
+		return v.V, nil
+	case "t":
+		return time.Parse(time.RFC3339Nano, v.V)
+	}
+	return nil, fmt.Errorf("未知类型 %q", v.T)
+}
+
+func cursorMac(payload []byte) []byte {
+	cursorSecretMu.RLock()
+	mac := hmac.New(sha256.New, cursorSecret)
+	cursorSecretMu.RUnlock()
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+//生成游标：base64(json).base64(hmac)
+func encodeCursor(signature string, prev bool, keys []interface{}) (string, error) {
+	c := cursor{Order: signature, Prev: prev}
+	for _, key := range keys {
+		v, err := newCursorValue(key)
+		if err != nil {
+			return "", err
+		}
+		c.Values = append(c.Values, v)
+	}
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(cursorMac(payload)), nil
+}
+
+//解析并校验游标
+func decodeCursor(token, signature string) (cursor, error) {
+	var c cursor
+	enc := base64.RawURLEncoding
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return c, ErrInvalidCursor
+	}
+	payload, err := enc.DecodeString(parts[0])
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	sum, err := enc.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(sum, cursorMac(payload)) {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	if c.Order != signature || len(c.Values) != len(strings.Split(signature, ",")) {
+		return c, fmt.Errorf("%w: 与排序字段不一致", ErrInvalidCursor)
+	}
+	for _, v := range c.Values {
+		if _, err := v.value(); err != nil {
+			return c, ErrInvalidCursor
+		}
+	}
+	return c, nil
+}
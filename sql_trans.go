@@ -0,0 +1,170 @@
+package corm
+
+import (
+	"errors"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+/**
+事务重试策略
+MaxAttempts 最大执行次数（包含第一次），小于 1 时按 1 处理
+BaseDelay 第一次重试前的等待时间，之后每次翻倍
+MaxDelay 单次等待时间上限，0 表示不限制
+Retryable 判断错误是否可重试，为空时使用 IsRetryable
+*/
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Retryable   func(err error) bool
+}
+
+/**
+默认重试策略：最多执行 3 次，等待 50ms 起，最长 1s
+*/
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   50 * time.Millisecond,
+		MaxDelay:    time.Second,
+	}
+}
+
+/**
+判断是否为可重试的错误：死锁、锁等待超时
+*/
+func IsRetryable(err error) bool {
+	err = translateErr(err)
+	return errors.Is(err, ErrDeadlock) || errors.Is(err, ErrLockTimeout)
+}
+
+/**
+第 attempt 次失败后的等待时间，指数退避并在 [delay/2, delay) 之间随机抖动
+*/
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << uint(attempt-1)
+	if delay <= 0 || (p.MaxDelay > 0 && delay > p.MaxDelay) {
+		delay = p.MaxDelay
+	}
+	if delay <= 1 {
+		return 0
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)))
+}
+
+/**
+事务作用域，记录提交、回滚后需要执行的回调
+嵌套事务通过保存点实现，每一层对应一个作用域
+*/
+type transScope struct {
+	depth         int
+	afterCommit   []func()
+	afterRollback []func()
+}
+
+func runHooks(hooks []func()) {
+	for _, hook := range hooks {
+		hook()
+	}
+}
+
+//开启事务并执行回调，回调返回错误时回滚
+func (db *Db) transaction(callable func(dbTrans *Db) error) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+
+	scope := &transScope{}
+	db.tx, db.scope = tx, scope
+	//回调 panic 时回滚事务并退出事务，再继续 panic，避免放回池中的 Db 仍持有已结束的事务
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback()
+			db.tx, db.scope = nil, nil
+			runHooks(scope.afterRollback)
+			panic(r)
+		}
+	}()
+	err = callable(db)
+	if err != nil {
+		_ = tx.Rollback()
+	} else {
+		err = tx.Commit()
+	}
+	//先退出事务再执行回调，回调中的查询不再使用已结束的事务
+	db.tx, db.scope = nil, nil
+
+	if err != nil {
+		runHooks(scope.afterRollback)
+		return err
+	}
+	runHooks(scope.afterCommit)
+	return nil
+}
+
+//在当前事务中通过保存点执行嵌套事务
+func (db *Db) savepoint(callable func(dbTrans *Db) error) error {
+	parent := db.scope
+	if parent == nil {
+		parent = &transScope{}
+	}
+	scope := &transScope{depth: parent.depth + 1}
+	name := "corm_sp_" + strconv.Itoa(scope.depth)
+
+	if _, err := db.tx.Exec("SAVEPOINT " + name); err != nil {
+		return err
+	}
+
+	db.scope = scope
+	defer func() {
+		if r := recover(); r != nil {
+			_, _ = db.tx.Exec("ROLLBACK TO SAVEPOINT " + name)
+			db.scope = parent
+			runHooks(scope.afterRollback)
+			panic(r)
+		}
+	}()
+	err := callable(db)
+	db.scope = parent
+	if err != nil {
+		_, _ = db.tx.Exec("ROLLBACK TO SAVEPOINT " + name)
+		runHooks(scope.afterRollback)
+		return err
+	}
+
+	if _, err = db.tx.Exec("RELEASE SAVEPOINT " + name); err != nil {
+		return err
+	}
+	//保存点释放后，回调随外层事务一起提交或回滚
+	parent.afterCommit = append(parent.afterCommit, scope.afterCommit...)
+	parent.afterRollback = append(parent.afterRollback, scope.afterRollback...)
+	return nil
+}
+
+//按重试策略执行事务，每次重试都会开启新的事务并重新执行整个回调
+func (db *Db) retryTransaction(callable func(dbTrans *Db) error) error {
+	policy := *db.retry
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+
+	for attempt := 1; ; attempt++ {
+		db.attempt = attempt
+		err := db.transaction(callable)
+		if err == nil {
+			return nil
+		}
+		if attempt >= policy.MaxAttempts || !retryable(err) {
+			logf("corm: transaction attempt %d failed, giving up: %v", attempt, err)
+			return err
+		}
+		delay := policy.backoff(attempt)
+		logf("corm: transaction attempt %d failed, retrying in %s: %v", attempt, delay, err)
+		time.Sleep(delay)
+	}
+}
@@ -0,0 +1,314 @@
+package corm
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+//Where、Having 支持的条件符号
+var operators = map[string]bool{
+	"=":       true,
+	"<>":      true,
+	"!=":      true,
+	">":       true,
+	">=":      true,
+	"<":       true,
+	"<=":      true,
+	LIKE:      true,
+	NOT_LIKE:  true,
+	IN:        true,
+	NOT_IN:    true,
+	BETWEEN:   true,
+	IS_NULL:   true,
+	NOT_NULL:  true,
+	NULL_SAFE: true,
+}
+
+func normalizeOperator(operator string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(operator), SPACE))
+}
+
+//行锁只能在事务中使用，SkipLocked、NoWait 需配合 LockForUpdate、LockForShare
+func (db *Db) validateLock() []error {
+	if db.lock == "" {
+		if db.wait != "" {
+			return []error{methodErr(lockMethods[db.wait], "需先调用 LockForUpdate 或 LockForShare")}
+		}
+		return nil
+	}
+	method := lockMethods[db.lock]
+	var errs []error
+	if db.tx == nil {
+		errs = append(errs, methodErr(method, "只能在事务中使用"))
+	}
+	if len(db.unions) > 0 {
+		errs = append(errs, methodErr(method, "不能用于联合查询"))
+	}
+	if _, err := dialect.Lock(db.lock, db.wait); err != nil {
+		if db.wait != "" {
+			method = lockMethods[db.wait]
+		}
+		errs = append(errs, methodErr(method, "%v", err))
+	}
+	return errs
+}
+
+var lockMethods = map[string]string{
+	FOR_UPDATE: "LockForUpdate",
+	FOR_SHARE:  "LockForShare",
+	SKIP_LOCK:  "SkipLocked",
+	NOWAIT:     "NoWait",
+}
+
+//子查询的错误
+func subErr(sub *Db) []error {
+	if sub == nil {
+		return nil
+	}
+	if err := sub.getErr(); err != nil {
+		return []error{fmt.Errorf("子查询: %w", err)}
+	}
+	return nil
+}
+
+func methodErr(method, format string, a ...interface{}) error {
+	return fmt.Errorf(method+": "+format, a...)
+}
+
+//添加where条件，method 为调用的构造方法，校验出错时用于提示
+func (db *Db) pushWhere(method, field, operator string, condition interface{}) *Db {
+	db.where = append(db.where, db.newWhere(method, field, operator, condition))
+	return db
+}
+
+//生成条件，条件值为 NULL 时，= 转换为 IS NULL，<>、!= 转换为 IS NOT NULL
+func (db *Db) newWhere(method, field, operator string, condition interface{}) where {
+	operator = normalizeOperator(operator)
+	if isNull(condition) {
+		switch operator {
+		case "=":
+			operator, condition = IS_NULL, nil
+		case "<>", "!=":
+			operator, condition = NOT_NULL, nil
+		}
+	}
+	return where{
+		method:    method,
+		field:     db.quoteColumn(method, field),
+		operator:  operator,
+		condition: condition,
+	}
+}
+
+//判断条件值是否为 NULL：nil、nil 指针或 Valid 为 false 的 sql.Null* 等 driver.Valuer
+func isNull(condition interface{}) bool {
+	if condition == nil {
+		return true
+	}
+	//与 database/sql 一致，nil 指针按 NULL 处理，避免调用值接收者的 Value 时 panic
+	if rv := reflect.ValueOf(condition); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return true
+	}
+	if valuer, ok := condition.(driver.Valuer); ok {
+		value, err := valuer.Value()
+		return err == nil && value == nil
+	}
+	return false
+}
+
+//添加原生where条件
+func (db *Db) pushWhereRaw(method, raw string, args []interface{}) *Db {
+	if strings.TrimSpace(raw) == "" {
+		db.pushErr(methodErr(method, "条件不能为空"))
+		return db
+	}
+	raw, args = db.bindArgs(method, raw, args)
+	db.where = append(db.where, where{
+		method:         method,
+		raw:            raw,
+		conditionArray: args,
+	})
+	return db
+}
+
+//添加子查询条件
+func (db *Db) pushWhereSub(method, field, operator string, sub *Db) *Db {
+	if sub == nil {
+		db.pushErr(methodErr(method, "子查询不能为空"))
+		return db
+	}
+	if field != "" {
+		field = db.quoteColumn(method, field)
+	}
+	db.where = append(db.where, where{
+		method:   method,
+		field:    field,
+		operator: operator,
+		sub:      sub,
+	})
+	return db
+}
+
+//添加分组条件，回调中的条件及错误合并到当前构造器
+func (db *Db) pushWhereGroup(method string, callable func(q *Db), or bool) *Db {
+	q := new(Db)
+	callable(q)
+	db.err = append(db.err, q.err...)
+	if len(q.where) == 0 {
+		return db
+	}
+	db.where = append(db.where, where{
+		method: method,
+		group:  q.where,
+		or:     or,
+	})
+	return db
+}
+
+//已有条件包含 OR 时合并为一个分组，避免之后追加的条件只作用于最后一个 OR 分支，不修改 db.where
+func (db *Db) groupedWhere(method string) []where {
+	for _, w := range db.where {
+		if w.or {
+			return []where{{method: method, group: db.where}}
+		}
+	}
+	return db.where[:len(db.where):len(db.where)]
+}
+
+//添加使用构造器的关联条件
+func (db *Db) pushJoinOn(method, direction, table string, callable func(on *Db)) *Db {
+	on := new(Db)
+	callable(on)
+	db.err = append(db.err, on.err...)
+	if len(on.where) == 0 {
+		db.pushErr(methodErr(method, "关联条件不能为空"))
+	}
+	db.join = append(db.join, join{
+		table:     db.quoteTable(method, table),
+		direction: direction,
+		onWhere:   on.where,
+	})
+	return db
+}
+
+/**
+生成SQL前校验构造的条件，返回全部错误
+*/
+func (db *Db) validate() []error {
+	var errs []error
+	if db.table == "" && !db.raw {
+		errs = append(errs, errors.New("未定义数据表"))
+	}
+	if db.limit < 0 {
+		errs = append(errs, methodErr("Limit", "不能为负数：%d", db.limit))
+	}
+	if db.offset < 0 {
+		errs = append(errs, methodErr("Offset", "不能为负数：%d", db.offset))
+	}
+	errs = append(errs, db.validateLock()...)
+	for _, w := range db.where {
+		errs = append(errs, w.validate()...)
+	}
+	for _, j := range db.join {
+		for _, w := range j.onWhere {
+			errs = append(errs, w.validate()...)
+		}
+	}
+	for _, f := range db.fields {
+		errs = append(errs, subErr(f.sub)...)
+	}
+	errs = append(errs, subErr(db.tabSub)...)
+	for _, u := range db.unions {
+		errs = append(errs, subErr(u.db)...)
+	}
+	for _, c := range db.ctes {
+		errs = append(errs, subErr(c.sub)...)
+		errs = append(errs, subErr(c.recursive)...)
+	}
+	for _, h := range db.having {
+		errs = append(errs, h.validate()...)
+	}
+	if db.count != "" && (len(db.groupBy) > 0 || len(db.having) > 0 || len(db.unions) > 0 || db.distinct) {
+		errs = append(errs, methodErr("CountDistinct", "不能与 GroupBy、Having、Distinct、Union 同时使用"))
+	}
+	for _, o := range db.orderBy {
+		if o.method != "OrderBy" {
+			continue
+		}
+		if o.by == "" {
+			errs = append(errs, methodErr(o.method, "字段 %s 未指定排序方向", o.field))
+		} else if o.by != "ASC" && o.by != "DESC" {
+			errs = append(errs, methodErr(o.method, "排序方向只能为 ASC 或 DESC：%q", o.by))
+		}
+	}
+	if db.insert != nil && len(db.insert) == 0 {
+		errs = append(errs, methodErr("Insert", "数据不能为空"))
+	}
+	if db.update != nil && len(db.update) == 0 {
+		errs = append(errs, methodErr("Update", "数据不能为空"))
+	}
+	return errs
+}
+
+//字段与字段比较支持的条件符号
+var columnOperators = map[string]bool{
+	"=":  true,
+	"<>": true,
+	"!=": true,
+	">":  true,
+	">=": true,
+	"<":  true,
+	"<=": true,
+}
+
+func (w where) validate() []error {
+	var errs []error
+	if w.group != nil {
+		for _, g := range w.group {
+			errs = append(errs, g.validate()...)
+		}
+		return errs
+	}
+	if w.sub != nil {
+		return subErr(w.sub)
+	}
+	if w.column != "" {
+		if !columnOperators[w.operator] {
+			errs = append(errs, methodErr(w.method, "不支持的条件符号 %q", w.operator))
+		}
+		return errs
+	}
+	if w.raw != "" {
+		if n := countPlaceholders(w.raw); n != len(w.conditionArray) {
+			errs = append(errs, methodErr(w.method, "占位符数量 %d 与参数数量 %d 不一致", n, len(w.conditionArray)))
+		}
+		return errs
+	}
+	if w.field == "" {
+		errs = append(errs, methodErr(w.method, "字段不能为空"))
+	}
+	if !operators[w.operator] {
+		return append(errs, methodErr(w.method, "不支持的条件符号 %q", w.operator))
+	}
+	switch w.operator {
+	case IN, NOT_IN:
+		if w.condition != nil {
+			errs = append(errs, methodErr(w.method, "%s 条件请使用 WhereIn、WhereNotIn", w.operator))
+		} else if len(w.conditionArray) == 0 {
+			errs = append(errs, methodErr(w.method, "字段 %s 的 %s 条件值不能为空", w.field, w.operator))
+		}
+	case BETWEEN:
+		if len(w.conditionArray) != 2 {
+			errs = append(errs, methodErr(w.method, "%s 条件请使用 WhereBetween", w.operator))
+		}
+	case IS_NULL, NOT_NULL, NULL_SAFE:
+	default:
+		if isNull(w.condition) {
+			errs = append(errs, methodErr(w.method, "字段 %s 的条件值为 NULL，只能使用 =、<>、!=、<=> 或 WhereNull", w.field))
+		}
+	}
+	return errs
+}
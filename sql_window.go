@@ -0,0 +1,282 @@
+package corm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/**
+窗口函数，格式：SelectWindow("rn", RowNumber().PartitionBy("group_id").OrderBy("created_at", "desc"))
+*/
+type WindowFunc struct {
+	fn        string
+	args      []interface{}
+	ref       string
+	partition []string
+	orderBy   []orderBy
+	frame     string
+	err       []error
+}
+
+type window struct {
+	name string
+	spec string
+}
+
+//窗口范围允许的关键字
+var frameWords = map[string]bool{
+	"ROWS": true, "RANGE": true, "BETWEEN": true, "AND": true, "UNBOUNDED": true,
+	"PRECEDING": true, "FOLLOWING": true, "CURRENT": true, "ROW": true,
+}
+
+func newWindow(fn string) *WindowFunc {
+	return &WindowFunc{fn: fn}
+}
+
+/**
+窗口定义，不含函数，用于 Window 命名窗口
+*/
+func Window() *WindowFunc {
+	return newWindow("")
+}
+
+/**
+行号 ROW_NUMBER()
+*/
+func RowNumber() *WindowFunc {
+	return newWindow("ROW_NUMBER()")
+}
+
+/**
+排名 RANK()，并列时跳过名次
+*/
+func Rank() *WindowFunc {
+	return newWindow("RANK()")
+}
+
+/**
+排名 DENSE_RANK()，并列时不跳过名次
+*/
+func DenseRank() *WindowFunc {
+	return newWindow("DENSE_RANK()")
+}
+
+/**
+前 offset 行的值 LAG(field, offset[, def])
+field 字段
+offset 偏移行数
+def 默认值，可不传
+*/
+func Lag(field string, offset int, def ...interface{}) *WindowFunc {
+	return newOffsetWindow("Lag", "LAG", field, offset, def)
+}
+
+/**
+后 offset 行的值 LEAD(field, offset[, def])
+field 字段
+offset 偏移行数
+def 默认值，可不传
+*/
+func Lead(field string, offset int, def ...interface{}) *WindowFunc {
+	return newOffsetWindow("Lead", "LEAD", field, offset, def)
+}
+
+func newOffsetWindow(method, fn, field string, offset int, def []interface{}) *WindowFunc {
+	w := newWindow("")
+	quoted := w.quoteColumn(method, field, false)
+	if offset < 0 {
+		w.pushErr(methodErr(method, "偏移行数不能为负数：%d", offset))
+	}
+	if len(def) > 1 {
+		w.pushErr(methodErr(method, "默认值只能有一个"))
+	}
+	w.fn = fn + "(" + quoted + COMMA + SPACE + strconv.Itoa(offset)
+	if len(def) > 0 {
+		w.fn += COMMA + SPACE + QUES
+		w.args = append(w.args, def[0])
+	}
+	w.fn += ")"
+	return w
+}
+
+/**
+窗口求和 SUM(field) OVER，常用于累计值
+*/
+func SumOver(field string) *WindowFunc {
+	w := newWindow("")
+	w.fn = "SUM(" + w.quoteColumn("SumOver", field, false) + ")"
+	return w
+}
+
+/**
+窗口计数 COUNT(field) OVER，field 可为 *
+*/
+func CountOver(field string) *WindowFunc {
+	w := newWindow("")
+	w.fn = "COUNT(" + w.quoteColumn("CountOver", field, true) + ")"
+	return w
+}
+
+/**
+分区字段，格式：PartitionBy("group_id", "type")
+*/
+func (w *WindowFunc) PartitionBy(field ...string) *WindowFunc {
+	for _, f := range field {
+		w.partition = append(w.partition, w.quoteColumn("PartitionBy", f, false))
+	}
+	return w
+}
+
+/**
+窗口内排序，格式：OrderBy("created_at", "desc")
+by asc或desc，其他值会返回错误
+*/
+func (w *WindowFunc) OrderBy(field, by string) *WindowFunc {
+	by = strings.ToUpper(strings.TrimSpace(by))
+	if by != "ASC" && by != "DESC" {
+		w.pushErr(methodErr("OrderBy", "排序方向只能为 ASC 或 DESC：%q", by))
+	}
+	w.orderBy = append(w.orderBy, orderBy{
+		method: "OrderBy",
+		field:  w.quoteColumn("OrderBy", field, false),
+		by:     by,
+	})
+	return w
+}
+
+/**
+窗口范围，格式：Frame("ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW")
+只能包含范围关键字及非负整数
+*/
+func (w *WindowFunc) Frame(frame string) *WindowFunc {
+	words := strings.Fields(strings.ToUpper(frame))
+	if len(words) == 0 || (words[0] != "ROWS" && words[0] != "RANGE") {
+		w.pushErr(methodErr("Frame", "窗口范围需以 ROWS 或 RANGE 开头：%q", frame))
+		return w
+	}
+	for _, word := range words {
+		if _, err := strconv.ParseUint(word, 10, 32); err != nil && !frameWords[word] {
+			w.pushErr(methodErr("Frame", "非法的窗口范围 %q", frame))
+			return w
+		}
+	}
+	w.frame = strings.Join(words, SPACE)
+	return w
+}
+
+/**
+使用 Window 定义的命名窗口，格式：RowNumber().Over("w")
+之后的 PartitionBy、OrderBy、Frame 在命名窗口的基础上追加
+*/
+func (w *WindowFunc) Over(name string) *WindowFunc {
+	quoted, ok := quoteIdent(name)
+	if !ok {
+		w.pushErr(methodErr("Over", "非法窗口名 %q", name))
+	}
+	w.ref = quoted
+	return w
+}
+
+func (w *WindowFunc) pushErr(err error) {
+	w.err = append(w.err, err)
+}
+
+func (w *WindowFunc) quoteColumn(method, field string, star bool) string {
+	quoted, err := quoteName(field, star)
+	if err == nil && quoted == "" {
+		err = fmt.Errorf("字段不能为空")
+	}
+	if err != nil {
+		w.pushErr(methodErr(method, "%v", err))
+		return field
+	}
+	return quoted
+}
+
+//生成 OVER 括号中的窗口定义
+func (w *WindowFunc) spec() string {
+	parts := make([]string, 0, 4)
+	if w.ref != "" {
+		parts = append(parts, w.ref)
+	}
+	if len(w.partition) > 0 {
+		parts = append(parts, "PARTITION BY "+strings.Join(w.partition, COMMA))
+	}
+	if len(w.orderBy) > 0 {
+		order := make([]string, 0, len(w.orderBy))
+		for _, o := range w.orderBy {
+			order = append(order, o.field+SPACE+o.by)
+		}
+		parts = append(parts, ORDER_BY+SPACE+strings.Join(order, COMMA))
+	}
+	if w.frame != "" {
+		parts = append(parts, w.frame)
+	}
+	return strings.Join(parts, SPACE)
+}
+
+func (w *WindowFunc) toSql() string {
+	if w.ref != "" && len(w.partition) == 0 && len(w.orderBy) == 0 && w.frame == "" {
+		return w.fn + " OVER " + w.ref
+	}
+	return w.fn + " OVER (" + w.spec() + ")"
+}
+
+/**
+窗口函数作为查询字段，格式：SelectWindow("rn", RowNumber().PartitionBy("group_id").OrderBy("created_at", "desc"))
+alias 别名
+w 窗口函数
+*/
+func (db *Db) SelectWindow(alias string, w *WindowFunc) *Db {
+	if w == nil || w.fn == "" {
+		db.pushErr(methodErr("SelectWindow", "缺少窗口函数"))
+		return db
+	}
+	quoted, ok := quoteIdent(alias)
+	if !ok {
+		db.pushErr(methodErr("SelectWindow", "非法别名 %q", alias))
+	}
+	for _, err := range w.err {
+		db.pushErr(methodErr("SelectWindow", "%w", err))
+	}
+	db.fields = append(db.fields, fieldExpr(w.toSql()+SPACE+AS+SPACE+quoted, w.args))
+	return db
+}
+
+/**
+命名窗口，生成 WINDOW name AS (...)，格式：
+Window("w", corm.Window().PartitionBy("group_id").OrderBy("created_at", "desc")).
+SelectWindow("rn", RowNumber().Over("w")).SelectWindow("total", SumOver("amount").Over("w"))
+name 窗口名
+w 窗口定义，不能包含窗口函数
+*/
+func (db *Db) Window(name string, w *WindowFunc) *Db {
+	if w == nil || w.fn != "" {
+		db.pushErr(methodErr("Window", "窗口定义请使用 corm.Window() 创建"))
+		return db
+	}
+	quoted, ok := quoteIdent(name)
+	if !ok {
+		db.pushErr(methodErr("Window", "非法窗口名 %q", name))
+	}
+	for _, err := range w.err {
+		db.pushErr(methodErr("Window", "%w", err))
+	}
+	db.windows = append(db.windows, window{name: quoted, spec: w.spec()})
+	return db
+}
+
+/**
+添加命名窗口
+*/
+func (db *Db) addWindow() {
+	if len(db.windows) == 0 {
+		return
+	}
+	list := make([]string, 0, len(db.windows))
+	for _, w := range db.windows {
+		list = append(list, w.name+SPACE+AS+" ("+w.spec+")")
+	}
+	db.writeBuf(WINDOW, SPACE, strings.Join(list, COMMA), SPACE)
+}